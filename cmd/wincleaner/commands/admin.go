@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// NewAdminCommand returns the cobra command for 'admin'
+func NewAdminCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "admin [command...]",
+		Short: "Restart with administrator privileges (launches interactive mode unless a command is given)",
+		Run: func(cmd *cobra.Command, args []string) {
+			w := core.Console()
+			if cleaner.IsAdmin() {
+				fmt.Fprintln(w, "Already running with administrator privileges.")
+				return
+			}
+			if len(args) == 0 {
+				args = []string{"interactive"}
+			}
+			if err := cleaner.RunAsAdminWithArgs(args); err != nil {
+				fmt.Fprintf(w, "Error requesting administrator privileges: %v\n", err)
+			}
+		},
+	}
+}
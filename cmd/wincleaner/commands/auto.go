@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// NewAutoCommand returns the cobra command for 'auto'
+func NewAutoCommand() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "auto",
+		Short: "Run the operations recommended by the system health findings",
+		Long: `Collect the system status, evaluate the health findings and build a plan
+of the operations that address them (for example temp, recycle and disk for low
+free space, or flushdns when name resolution fails). The plan is shown and run
+after confirmation, or straight away with --yes.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			_, health, ok := collectHealth(cmd.Context())
+			if !ok {
+				return
+			}
+			plan := cleaner.PlanRemediation(health)
+			core.RecordPlan(plan)
+
+			w := core.Console()
+			core.DisplayHealth(health)
+			if len(plan) == 0 {
+				fmt.Fprintln(w, "\nNo operations are recommended.")
+				core.Logger.Info("Auto: no operations recommended")
+				return
+			}
+			displayPlan(plan)
+
+			if !yes && !confirm(fmt.Sprintf("Run these %d operations? [y/N]: ", len(plan))) {
+				fmt.Fprintln(w, "No operations were run.")
+				core.Logger.Info("Auto: plan declined")
+				return
+			}
+			for _, step := range plan {
+				if op, found := cleaner.LookupOperation(step.Operation); found {
+					core.RunRegistered(cmd.Context(), op)
+				}
+				if cmd.Context().Err() != nil {
+					break
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Run the plan without asking for confirmation")
+	return cmd
+}
+
+// displayPlan prints the recommended operations with the findings behind them
+func displayPlan(plan []cleaner.PlanStep) {
+	w := core.Console()
+	fmt.Fprintln(w, "\nRecommended Operations:")
+	fmt.Fprintln(w, "------------------------")
+	for i, step := range plan {
+		var notes []string
+		if step.RequiresAdmin {
+			notes = append(notes, "requires administrator")
+		}
+		if step.Destructive {
+			notes = append(notes, "deletes data")
+		}
+		suffix := ""
+		if len(notes) > 0 {
+			suffix = " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%d. %s [%s]%s\n", i+1, step.Name, step.Operation, suffix)
+		for _, reason := range step.Reasons {
+			fmt.Fprintf(w, "     because %s\n", reason)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// confirm asks a yes/no question on the console; anything but y or yes is a no
+func confirm(prompt string) bool {
+	fmt.Fprint(core.Console(), prompt)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
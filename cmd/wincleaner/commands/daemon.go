@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+)
+
+// NewDaemonCommand returns the cobra command for 'daemon'
+func NewDaemonCommand() *cobra.Command {
+	var next bool
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run the configured maintenance schedules until stopped",
+		Long: `Run the maintenance schedules from the 'schedules' section of the config file
+until stopped with Ctrl-C. Each schedule runs its operations at the times given by
+its cron expression, outside its quiet hours and only while its idle and AC power
+conditions hold. A schedule never overlaps itself, and runs of different schedules
+are serialized. Results are recorded in the history. When api.token or
+api.client_ca is set, the REST API is served as well, as by 'wincleaner serve'.
+'wincleaner service install' runs the same loop as a Windows service.
+
+With --next, the schedules are validated and their next run times printed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if next {
+				runs, err := core.NextRuns(time.Now())
+				if err != nil {
+					return err
+				}
+				for _, run := range runs {
+					fmt.Fprintf(core.Console(), "%-20s %-20s %s\n", run.Schedule, run.Cron, run.Next.Format("2006-01-02 15:04 MST"))
+				}
+				return nil
+			}
+			return core.RunDaemon(cmd.Context())
+		},
+	}
+	cmd.Flags().BoolVar(&next, "next", false, "Validate the schedules and show when each runs next")
+	return cmd
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// NewHistoryCommand returns the cobra command for 'history'
+func NewHistoryCommand() *cobra.Command {
+	var window string
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show disk space, temp folder size and operation outcomes over time",
+		Long: `Show how the system has changed over time, from the status snapshots and
+operation results recorded by earlier runs: free space per drive with its growth
+rate and projected days until full, temp folder size, and per-operation outcomes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			age, err := parseAge(window)
+			if err != nil {
+				return err
+			}
+			var since time.Time
+			if age > 0 {
+				since = time.Now().Add(-age)
+			}
+			entries, err := cleaner.LoadHistory(core.HistoryDir(), since)
+			if err != nil {
+				fmt.Fprintf(core.Console(), "Error reading history: %v\n", err)
+				core.RecordError(fmt.Errorf("reading history: %w", err))
+				return nil
+			}
+			report := cleaner.AnalyzeHistory(entries, since)
+			core.RecordHistory(report)
+			if !core.Structured() {
+				displayHistory(report)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&window, "since", "30d", "How far back to look, e.g. 30d, 12h or 0 for all history")
+	return cmd
+}
+
+// displayHistory prints the trends and a day-by-day table
+func displayHistory(report *cleaner.HistoryReport) {
+	w := core.Console()
+	if report.Entries == 0 {
+		fmt.Fprintln(w, "No history has been recorded yet. Run 'wincleaner status' to take a snapshot.")
+		return
+	}
+
+	fmt.Fprintf(w, "\n=== History (%d entries) ===\n", report.Entries)
+	if len(report.Disks) > 0 {
+		fmt.Fprintln(w, "\nDisk Free Space:")
+		fmt.Fprintln(w, "------------------------")
+		for _, d := range report.Disks {
+			fmt.Fprintf(w, "Drive %s: %s -> %s free of %s (%d samples)\n", d.Drive, cleaner.FormatBytes(float64(d.FirstFree)), cleaner.FormatBytes(float64(d.LastFree)), cleaner.FormatBytes(float64(d.TotalBytes)), d.Samples)
+			switch {
+			case d.UsedGrowthPerDay == nil:
+				fmt.Fprintln(w, "  Growth: not enough history")
+			case d.DaysUntilFull != nil:
+				fmt.Fprintf(w, "  Growth: %s/day, full in about %.0f days\n", formatRate(*d.UsedGrowthPerDay), *d.DaysUntilFull)
+			default:
+				fmt.Fprintf(w, "  Growth: %s/day, not filling\n", formatRate(*d.UsedGrowthPerDay))
+			}
+		}
+	}
+
+	if t := report.Temp; t != nil {
+		fmt.Fprintln(w, "\nTemporary Files:")
+		fmt.Fprintln(w, "------------------------")
+		fmt.Fprintf(w, "%s -> %s (%d samples)", cleaner.FormatBytes(float64(t.FirstBytes)), cleaner.FormatBytes(float64(t.LastBytes)), t.Samples)
+		if t.GrowthPerDay != nil {
+			fmt.Fprintf(w, ", %s/day", formatRate(*t.GrowthPerDay))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Operations) > 0 {
+		fmt.Fprintln(w, "\nOperations:")
+		fmt.Fprintln(w, "------------------------")
+		for _, op := range report.Operations {
+			fmt.Fprintf(w, "%-12s %3d runs  %3d failed  %10s freed  last %s (%s)\n", op.Operation, op.Runs, op.Failures, cleaner.FormatBytes(float64(op.BytesFreed)), op.LastRun.Local().Format("2006-01-02 15:04"), op.LastStatus)
+		}
+	}
+
+	drives := make([]string, 0, len(report.Disks))
+	for _, d := range report.Disks {
+		drives = append(drives, d.Drive)
+	}
+	fmt.Fprintln(w, "\nBy Day:")
+	fmt.Fprintln(w, "------------------------")
+	for _, day := range report.Days {
+		var cols []string
+		for _, drive := range drives {
+			if free, ok := day.FreeBytes[drive]; ok {
+				cols = append(cols, fmt.Sprintf("%s %s free", drive, cleaner.FormatBytes(float64(free))))
+			}
+		}
+		if day.TempFolderBytes != nil {
+			cols = append(cols, "temp "+cleaner.FormatBytes(float64(*day.TempFolderBytes)))
+		}
+		if day.HealthScore != nil {
+			cols = append(cols, fmt.Sprintf("health %d", *day.HealthScore))
+		}
+		if day.OperationsRun > 0 {
+			cols = append(cols, fmt.Sprintf("%d operations, %d failed", day.OperationsRun, day.OperationsFailed))
+		}
+		fmt.Fprintf(w, "%s  %s\n", day.Date, strings.Join(cols, ", "))
+	}
+}
+
+// formatRate formats a signed byte rate
+func formatRate(bytes float64) string {
+	if bytes < 0 {
+		return "-" + cleaner.FormatBytes(-bytes)
+	}
+	return "+" + cleaner.FormatBytes(bytes)
+}
@@ -11,7 +11,7 @@ func NewInteractiveCommand() *cobra.Command {
 		Use:   "interactive",
 		Short: "Launch the interactive menu interface",
 		Run: func(cmd *cobra.Command, args []string) {
-			interactive.RunInteractiveMode()
+			interactive.RunInteractiveMode(cmd.Context())
 		},
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+)
+
+// NewServeMetricsCommand returns the cobra command for 'serve-metrics'
+func NewServeMetricsCommand() *cobra.Command {
+	var listen string
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "serve-metrics",
+		Short: "Serve system status and operation metrics for Prometheus",
+		Long: `Serve a Prometheus /metrics endpoint publishing the system status (per-drive
+size and free space, uptime, pending reboot, memory, temp folder size), the health
+score and findings, and per-operation counters from the run history (runs,
+failures, bytes freed, last run time).
+
+The status is collected once per interval; scrapes return the latest collection.
+The address and interval default to the 'metrics' section of the config file.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("listen") {
+				listen = core.Config.Metrics.Listen
+			}
+			if !cmd.Flags().Changed("interval") {
+				interval = core.Config.Metrics.Interval
+			}
+			return core.ServeMetrics(cmd.Context(), listen, interval)
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", core.DefaultMetricsConfig.Listen, "Address to listen on")
+	cmd.Flags().DurationVar(&interval, "interval", core.DefaultMetricsConfig.Interval, "How often to collect the system status")
+	return cmd
+}
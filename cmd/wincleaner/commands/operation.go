@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// NewOperationCommand returns the cobra command for a registered cleaner operation
+func NewOperationCommand(op cleaner.Operation) *cobra.Command {
+	return &cobra.Command{
+		Use:   op.ID,
+		Short: op.Description,
+		Run: func(cmd *cobra.Command, args []string) {
+			core.RunRegistered(cmd.Context(), op)
+		},
+	}
+}
+
+// NewOperationCommands returns one cobra command per registered cleaner operation
+func NewOperationCommands() []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, op := range cleaner.Operations() {
+		cmds = append(cmds, NewOperationCommand(op))
+	}
+	return cmds
+}
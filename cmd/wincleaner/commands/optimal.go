@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/user/windows_health/cmd/wincleaner/core"
 	"github.com/user/windows_health/pkg/cleaner"
@@ -8,11 +12,116 @@ import (
 
 // NewOptimalCommand returns the cobra command for 'optimal'
 func NewOptimalCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "optimal",
+	var rollback bool
+	var profilePath string
+	cmd := &cobra.Command{
+		Use:   "optimal [--rollback [run-id]]",
 		Short: "Apply optimal Windows settings (e.g., disable Fast Boot)",
-		Run: func(cmd *cobra.Command, args []string) {
-			core.RunOperation(cmd.Context(), "Set Optimal Windows Settings", func() error { return cleaner.SetOptimalWindowsSettings(core.Verbose) }, 0)
+		Long: `Apply optimal Windows settings (e.g., disable Fast Boot).
+
+With --profile, or an 'optimal' section in the config file, the settings are
+applied unattended. Otherwise an interactive wizard asks for each one.
+
+The previous value of every setting changed is saved in a change journal.
+--rollback restores the settings changed by a run, or by the most recent
+run that has not been rolled back when no run ID is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if rollback {
+				runID := "latest"
+				if len(args) > 0 {
+					runID = args[0]
+				}
+				core.RunOperation(cmd.Context(), "Roll Back Windows Settings", func(ctx context.Context, opts cleaner.Options) (*cleaner.OperationResult, error) {
+					return cleaner.RollbackChanges(ctx, opts, core.JournalDir(), runID)
+				}, 0)
+				return nil
+			}
+			if len(args) > 0 {
+				return errors.New("a run ID is only accepted with --rollback")
+			}
+
+			switch {
+			case profilePath != "":
+				profile, err := cleaner.LoadOptimalProfile(profilePath)
+				if err != nil {
+					return fmt.Errorf("invalid profile: %w", err)
+				}
+				core.ApplyOptimalProfile(cmd.Context(), profile)
+			case core.Config.Optimal != nil:
+				core.ApplyOptimalProfile(cmd.Context(), *core.Config.Optimal)
+			default:
+				core.RunOperation(cmd.Context(), "Set Optimal Windows Settings", cleaner.SetOptimalWindowsSettings, 0)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&rollback, "rollback", false, "Restore the settings changed by an earlier run (default: the latest)")
+	cmd.Flags().StringVar(&profilePath, "profile", "", "Apply a settings profile file unattended ('recommended' for the built-in profile)")
+	cmd.AddCommand(newOptimalAuditCommand())
+	return cmd
+}
+
+// newOptimalAuditCommand returns the cobra command for 'optimal audit'
+func newOptimalAuditCommand() *cobra.Command {
+	var profilePath string
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Compare the current settings with a desired profile without changing anything",
+		Long: `Compare the current settings with a desired profile without changing anything.
+
+The profile is taken from --profile, else the 'optimal' section of the config
+file, else the built-in recommended profile.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile := cleaner.RecommendedProfile
+			switch {
+			case profilePath != "":
+				var err error
+				if profile, err = cleaner.LoadOptimalProfile(profilePath); err != nil {
+					return fmt.Errorf("invalid profile: %w", err)
+				}
+			case core.Config.Optimal != nil:
+				profile = *core.Config.Optimal
+			}
+
+			audit, err := cleaner.AuditOptimalProfile(cmd.Context(), cleaner.WinRegistry, profile)
+			if err != nil {
+				fmt.Fprintf(core.Console(), "Error auditing settings: %v\n", err)
+				core.RecordError(fmt.Errorf("auditing settings: %w", err))
+			}
+			if audit == nil {
+				return nil
+			}
+			core.RecordAudit(audit)
+			if !core.Structured() {
+				displayAudit(audit)
+			}
+			return nil
 		},
 	}
-} 
\ No newline at end of file
+	cmd.Flags().StringVar(&profilePath, "profile", "", "Profile file to audit against ('recommended' for the built-in profile)")
+	return cmd
+}
+
+// displayAudit prints a settings audit as a text table
+func displayAudit(audit *cleaner.SettingsAudit) {
+	w := core.Console()
+	fmt.Fprintln(w, "\n=== Settings Audit ===")
+	for _, item := range audit.Items {
+		verdict := "OK   "
+		if !item.Compliant {
+			verdict = "DRIFT"
+		}
+		current := item.Current
+		if item.Error != "" {
+			verdict, current = "ERROR", "unreadable: "+item.Error
+		}
+		fmt.Fprintf(w, "[%s] %-45s desired: %-16s current: %s\n", verdict, item.Name, item.Desired, current)
+	}
+	if audit.Compliant {
+		fmt.Fprintln(w, "\nAll audited settings are compliant.")
+	} else {
+		fmt.Fprintf(w, "\n%d of %d settings are not compliant. Apply the profile with: wincleaner optimal --profile <file>\n", audit.NonCompliant(), len(audit.Items))
+	}
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// NewPlanCommand returns the cobra command for 'plan' and its subcommands
+func NewPlanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "List and run the maintenance plans defined in the config file",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the maintenance plans and the order their steps run in",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			core.RecordPlans(core.Config.Plans)
+			if core.Structured() {
+				return
+			}
+			w := core.Console()
+			if len(core.Config.Plans) == 0 {
+				fmt.Fprintln(w, "No plans are configured.")
+				return
+			}
+			for i, plan := range core.Config.Plans {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				displayMaintenancePlan(w, plan)
+			}
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a maintenance plan",
+		Long: `Run the steps of a maintenance plan in order, each after the steps it depends on.
+A step whose condition does not hold is skipped. A step that fails stops the plan,
+unless it is marked continue_on_error.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			var names []string
+			for _, plan := range core.Config.Plans {
+				names = append(names, plan.Name)
+			}
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, ok := core.FindPlan(args[0])
+			if !ok {
+				var names []string
+				for _, plan := range core.Config.Plans {
+					names = append(names, plan.Name)
+				}
+				if len(names) == 0 {
+					return fmt.Errorf("unknown plan %q: no plans are configured", args[0])
+				}
+				return fmt.Errorf("unknown plan %q (configured: %s)", args[0], strings.Join(names, ", "))
+			}
+			core.RunPlan(cmd.Context(), plan)
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// displayMaintenancePlan prints a plan's steps in execution order with their settings
+func displayMaintenancePlan(w io.Writer, plan cleaner.MaintenancePlan) {
+	fmt.Fprintf(w, "%s", plan.Name)
+	if plan.Description != "" {
+		fmt.Fprintf(w, ": %s", plan.Description)
+	}
+	fmt.Fprintln(w)
+	steps, err := plan.Order()
+	if err != nil {
+		fmt.Fprintf(w, "  %v\n", err)
+		return
+	}
+	for i, step := range steps {
+		name := step.Operation
+		if op, ok := cleaner.LookupOperation(step.Operation); ok {
+			name = fmt.Sprintf("%s [%s]", op.Name, op.ID)
+		}
+		var notes []string
+		if len(step.After) > 0 {
+			notes = append(notes, "after "+strings.Join(step.After, ", "))
+		}
+		if step.Timeout > 0 {
+			notes = append(notes, "timeout "+step.Timeout.String())
+		}
+		if step.ContinueOnError {
+			notes = append(notes, "continues on error")
+		}
+		if c := step.When; c != nil {
+			if c.Admin {
+				notes = append(notes, "only as administrator")
+			}
+			var limits []string
+			if c.FreeSpaceBelow > 0 {
+				limits = append(limits, c.FreeSpaceBelow.String())
+			}
+			if c.FreePercentBelow > 0 {
+				limits = append(limits, fmt.Sprintf("%g%%", c.FreePercentBelow))
+			}
+			if len(limits) > 0 {
+				drive := c.Drive
+				if drive == "" {
+					drive = cleaner.SystemDrive()
+				}
+				notes = append(notes, fmt.Sprintf("only if %s has less than %s free", drive, strings.Join(limits, " or ")))
+			}
+		}
+		suffix := ""
+		if len(notes) > 0 {
+			suffix = " (" + strings.Join(notes, "; ") + ")"
+		}
+		fmt.Fprintf(w, "  %d. %s%s\n", i+1, name, suffix)
+	}
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// NewRestoreCommand returns the cobra command for 'restore'
+func NewRestoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <run-id>",
+		Short: "Restore files quarantined by an earlier run",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runID := args[0]
+			core.RunOperation(cmd.Context(), "Restore Quarantine "+runID, func(ctx context.Context, opts cleaner.Options) (*cleaner.OperationResult, error) {
+				return cleaner.RestoreQuarantine(ctx, opts, core.QuarantineDir(), runID)
+			}, 0)
+		},
+	}
+}
+
+// NewQuarantineCommand returns the cobra command for 'quarantine' and its subcommands
+func NewQuarantineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quarantine",
+		Short: "Inspect and purge the quarantine store",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List quarantined runs",
+		Run: func(cmd *cobra.Command, args []string) {
+			w := core.Console()
+			runs, err := cleaner.ListQuarantine(core.QuarantineDir())
+			if err != nil {
+				fmt.Fprintf(w, "Error reading quarantine: %v\n", err)
+				core.RecordError(fmt.Errorf("reading quarantine: %w", err))
+				return
+			}
+			core.RecordQuarantine(runs)
+			if core.Structured() {
+				return
+			}
+			if len(runs) == 0 {
+				fmt.Fprintln(w, "The quarantine store is empty.")
+				return
+			}
+			for _, run := range runs {
+				if run.Error != "" {
+					fmt.Fprintf(w, "%s  %s  unreadable  %s  (%s)\n", run.RunID, run.Created.Format("2006-01-02 15:04:05"), cleaner.FormatBytes(float64(run.Size)), run.Error)
+					continue
+				}
+				fmt.Fprintf(w, "%s  %s  %d files  %s\n", run.RunID, run.Created.Format("2006-01-02 15:04:05"), len(run.Entries), cleaner.FormatBytes(float64(run.Size)))
+			}
+		},
+	})
+
+	var olderThan string
+	purge := &cobra.Command{
+		Use:   "purge",
+		Short: "Permanently delete quarantined runs older than --older-than",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxAge, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+			core.RunOperation(cmd.Context(), "Purge Quarantine", func(ctx context.Context, opts cleaner.Options) (*cleaner.OperationResult, error) {
+				return cleaner.PurgeQuarantine(ctx, opts, core.QuarantineDir(), maxAge)
+			}, 0)
+			return nil
+		},
+	}
+	purge.Flags().StringVar(&olderThan, "older-than", "30d", "Minimum age of runs to purge, e.g. 30d, 12h or 0 for everything")
+	cmd.AddCommand(purge)
+
+	return cmd
+}
+
+// parseAge parses a Go duration, additionally accepting whole days such as "30d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: %w", s, err)
+	}
+	return d, nil
+}
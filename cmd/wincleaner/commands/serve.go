@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+)
+
+// NewServeCommand returns the cobra command for 'serve'
+func NewServeCommand() *cobra.Command {
+	var listen string
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a REST API for running operations remotely",
+		Long: `Serve a REST API for listing and running operations and reading system status:
+
+  GET    /api/v1/operations         list operations
+  GET    /api/v1/status             system status and health findings
+  POST   /api/v1/jobs               start a job: {"operations": ["temp", "recycle"], "dry_run": false}
+  GET    /api/v1/jobs               list jobs
+  GET    /api/v1/jobs/{id}          job state and results
+  GET    /api/v1/jobs/{id}/log      stream the job's output (?follow=false for a snapshot)
+  POST   /api/v1/jobs/{id}/cancel   cancel a job (also DELETE /api/v1/jobs/{id})
+
+Jobs run one at a time, each with its own run ID for quarantine and rollback.
+Requests must carry "Authorization: Bearer <token>" (api.token in the config file or
+the WINCLEANER_API_TOKEN environment variable), or a client certificate signed by
+api.client_ca over HTTPS, or both.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := core.Config.API
+			if cmd.Flags().Changed("listen") {
+				cfg.Listen = listen
+			}
+			return core.ServeAPI(cmd.Context(), cfg)
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", core.DefaultAPIConfig.Listen, "Address to listen on")
+	return cmd
+}
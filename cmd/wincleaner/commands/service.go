@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/user/windows_health/cmd/wincleaner/core"
+)
+
+// NewServiceCommand returns the cobra command for 'service' and its subcommands
+func NewServiceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Install and control wincleaner as a Windows service",
+		Long: `Install wincleaner as a Windows service that runs the configured schedules, and the
+REST API when api.token or api.client_ca is set, without anyone logged on. The
+service runs 'wincleaner service run' with the config file given by --config (default
+wincleaner.yaml in the current folder). Installing, uninstalling, starting and
+stopping the service require administrator privileges.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "install",
+		Short: "Register the service to start automatically with the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Refuse a config the service would exit on straight away
+			if err := core.CheckService(); err != nil {
+				return fmt.Errorf("%s: %w", core.ConfigFile, err)
+			}
+			if err := core.InstallService(core.ConfigFile); err != nil {
+				return err
+			}
+			fmt.Fprintf(core.Console(), "Installed service %s. Start it with: wincleaner service start\n", core.ServiceName)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop and remove the service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.UninstallService(); err != nil {
+				return err
+			}
+			fmt.Fprintf(core.Console(), "Removed service %s.\n", core.ServiceName)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "start",
+		Short: "Start the service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.StartService(); err != nil {
+				return err
+			}
+			fmt.Fprintf(core.Console(), "Service %s is running.\n", core.ServiceName)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "stop",
+		Short: "Stop the service, canceling any running operation",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := core.StopService(); err != nil {
+				return err
+			}
+			fmt.Fprintf(core.Console(), "Service %s is stopped.\n", core.ServiceName)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether the service is installed and running",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := core.QueryService()
+			if err != nil {
+				return err
+			}
+			core.RecordService(info)
+			if core.Structured() {
+				return nil
+			}
+			w := core.Console()
+			if !info.Installed {
+				fmt.Fprintf(w, "Service %s is not installed.\n", info.Name)
+				return nil
+			}
+			fmt.Fprintf(w, "Service:    %s\n", info.Name)
+			fmt.Fprintf(w, "State:      %s\n", info.State)
+			fmt.Fprintf(w, "Start type: %s\n", info.StartType)
+			fmt.Fprintf(w, "Command:    %s\n", info.Command)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "run",
+		Short: "Run the schedules and API; the service's entry point, or in the foreground",
+		Long: `Run the configured schedules, and the REST API when it is configured. Started by the
+service control manager, this is the service's entry point; started from a console, on
+Windows or elsewhere, it runs in the foreground until Ctrl-C, like 'wincleaner daemon'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return core.RunService(cmd.Context())
+		},
+	})
+
+	return cmd
+}
@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/user/windows_health/cmd/wincleaner/core"
 	"github.com/user/windows_health/pkg/cleaner"
@@ -11,18 +13,40 @@ import (
 func NewStatusCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "status",
-		Short: "Display system status information",
+		Short: "Display system status information and health findings",
 		Run: func(cmd *cobra.Command, args []string) {
-			core.Logger.Info("Retrieving system status...")
-			fmt.Println("Retrieving system status...")
-			status, err := cleaner.GetSystemStatus()
-			if err != nil {
-				fmt.Printf("Error retrieving system status: %v\n", err)
-				core.Logger.Errorf("Error retrieving system status: %v", err)
-			} else {
+			status, health, ok := collectHealth(cmd.Context())
+			if !ok {
+				return
+			}
+			if !core.Structured() {
 				core.DisplaySystemStatus(status)
-				core.Logger.Info("System status displayed successfully.")
+				core.DisplayHealth(health)
 			}
+			core.Logger.Info("System status displayed successfully.")
 		},
 	}
-} 
\ No newline at end of file
+}
+
+// collectHealth gathers the system status, evaluates it against the configured
+// thresholds and records both in the report. It reports false if the status could
+// not be collected, after recording the error.
+func collectHealth(ctx context.Context) (*cleaner.SystemStatus, *cleaner.HealthReport, bool) {
+	core.Logger.Info("Retrieving system status...")
+	fmt.Fprintln(core.Console(), "Retrieving system status...")
+	status, err := cleaner.GetSystemStatus(ctx)
+	if err != nil {
+		fmt.Fprintf(core.Console(), "Error retrieving system status: %v\n", err)
+		core.Logger.Errorf("Error retrieving system status: %v", err)
+		core.RecordError(fmt.Errorf("retrieving system status: %w", err))
+		return nil, nil, false
+	}
+	for name, reason := range status.Errors {
+		core.Logger.Warnf("Could not collect %s status: %s", name, reason)
+	}
+	health := cleaner.EvaluateHealth(status, core.Config.Health)
+	core.RecordStatus(status)
+	core.RecordHealth(health)
+	core.Logger.Infof("Health score %d (%s), %d findings need attention", health.Score, health.Verdict, len(health.Problems()))
+	return status, health, true
+}
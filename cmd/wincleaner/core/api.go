@@ -0,0 +1,280 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// APITokenEnv names the environment variable that supplies the API token, so that it
+// does not have to be written into the config file
+const APITokenEnv = "WINCLEANER_API_TOKEN"
+
+// APIConfig configures the 'serve' REST API
+type APIConfig struct {
+	// Listen is the address of the HTTP server
+	Listen string `yaml:"listen"`
+	// Token, when set, must be sent as "Authorization: Bearer <token>"
+	Token string `yaml:"token"`
+	// TLSCert and TLSKey enable HTTPS
+	TLSCert string `yaml:"tls_cert"`
+	TLSKey  string `yaml:"tls_key"`
+	// ClientCA, when set, requires clients to present a certificate signed by this CA (mTLS)
+	ClientCA string `yaml:"client_ca"`
+}
+
+// DefaultAPIConfig is used for settings the config file does not override
+var DefaultAPIConfig = APIConfig{Listen: "127.0.0.1:8719"}
+
+// OperationInfo describes a registered operation in API responses
+type OperationInfo struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	Description   string        `json:"description"`
+	RequiresAdmin bool          `json:"requires_admin"`
+	Destructive   bool          `json:"destructive"`
+	Timeout       time.Duration `json:"timeout_ns,omitempty"`
+}
+
+// JobRequest is the body of POST /api/v1/jobs
+type JobRequest struct {
+	// Operations are run in the given order
+	Operations []string `json:"operations"`
+	DryRun     bool     `json:"dry_run"`
+}
+
+// StatusResponse is the body of GET /api/v1/status
+type StatusResponse struct {
+	Status *cleaner.SystemStatus `json:"status"`
+	Health *cleaner.HealthReport `json:"health"`
+}
+
+// apiServer holds the state behind the API handlers
+type apiServer struct {
+	jobs  *JobManager
+	token string
+}
+
+// NewAPIHandler returns the API's routes; a non-empty token is required on every request
+func NewAPIHandler(jobs *JobManager, token string) http.Handler {
+	s := &apiServer{jobs: jobs, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/operations", s.listOperations)
+	mux.HandleFunc("GET /api/v1/status", s.status)
+	mux.HandleFunc("GET /api/v1/jobs", s.listJobs)
+	mux.HandleFunc("POST /api/v1/jobs", s.startJob)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", s.getJob)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/log", s.jobLog)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/cancel", s.cancelJob)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", s.cancelJob)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the bearer token, when one is configured.
+// Client certificates are verified by the TLS layer before a request gets here.
+func (s *apiServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wincleaner"`)
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				Logger.Warnf("API: rejected unauthenticated request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+				return
+			}
+		}
+		Logger.Infof("API: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as the response body
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes an error response
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func (s *apiServer) listOperations(w http.ResponseWriter, r *http.Request) {
+	var ops []OperationInfo
+	for _, op := range cleaner.Operations() {
+		ops = append(ops, OperationInfo{
+			ID:            op.ID,
+			Name:          op.Name,
+			Description:   op.Description,
+			RequiresAdmin: op.RequiresAdmin,
+			Destructive:   op.Destructive,
+			Timeout:       op.Timeout,
+		})
+	}
+	writeJSON(w, http.StatusOK, ops)
+}
+
+func (s *apiServer) status(w http.ResponseWriter, r *http.Request) {
+	status, err := cleaner.GetSystemStatus(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, StatusResponse{Status: status, Health: cleaner.EvaluateHealth(status, Config.Health)})
+}
+
+func (s *apiServer) listJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.List())
+}
+
+func (s *apiServer) startJob(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	job, err := s.jobs.Start(req.Operations, req.DryRun)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *apiServer) getJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// jobLog streams a job's console output, following it until the job finishes unless
+// ?follow=false is given
+func (s *apiServer) jobLog(w http.ResponseWriter, r *http.Request) {
+	log, ok := s.jobs.Log(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", r.PathValue("id")))
+		return
+	}
+	follow := r.URL.Query().Get("follow") != "false"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := w.(http.Flusher)
+
+	offset := 0
+	for {
+		data, next, closed, changed := log.ReadFrom(offset)
+		offset = next
+		if len(data) > 0 {
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if closed || !follow {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+func (s *apiServer) cancelJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.jobs.Cancel(r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrJobFinished):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// APIConfigured reports whether authentication is set up for the API, which is what
+// makes the daemon serve it
+func APIConfigured(cfg APIConfig) bool {
+	return cfg.Token != "" || cfg.ClientCA != "" || os.Getenv(APITokenEnv) != ""
+}
+
+// ServeAPI serves the REST API until ctx is canceled. It refuses to start without a
+// bearer token or client certificate verification.
+func ServeAPI(ctx context.Context, cfg APIConfig) error {
+	if token := os.Getenv(APITokenEnv); token != "" {
+		cfg.Token = token
+	}
+	if cfg.Token == "" && cfg.ClientCA == "" {
+		return fmt.Errorf("the API requires authentication: set api.token (or %s) or api.client_ca", APITokenEnv)
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return errors.New("api.tls_cert and api.tls_key must be set together")
+	}
+	if cfg.ClientCA != "" && cfg.TLSCert == "" {
+		return errors.New("api.client_ca requires api.tls_cert and api.tls_key")
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           NewAPIHandler(NewJobManager(ctx), cfg.Token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if cfg.ClientCA != "" {
+		pem, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return fmt.Errorf("reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", cfg.ClientCA)
+		}
+		server.TLSConfig = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert, MinVersion: tls.VersionTLS12}
+	}
+
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cancelGrace)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	})
+
+	scheme := "http"
+	if cfg.TLSCert != "" {
+		scheme = "https"
+	}
+	fmt.Fprintf(Console(), "Serving the API on %s://%s/api/v1/. Press Ctrl-C to stop.\n", scheme, cfg.Listen)
+	Logger.Infof("Serving the API on %s://%s (bearer token: %v, client certificates: %v)", scheme, cfg.Listen, cfg.Token != "", cfg.ClientCA != "")
+
+	var err error
+	if cfg.TLSCert != "" {
+		err = server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	Logger.Info("API server stopped")
+	return nil
+}
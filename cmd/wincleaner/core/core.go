@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -17,44 +21,210 @@ import (
 // default_ops: list of command names to run by default
 // log_file: path to the log file
 // timeout: global timeout for operations
-// timeouts: per-operation timeout overrides
-// json_output: toggle JSON output mode for supported commands
+// timeouts: per-operation timeout overrides, keyed by operation ID or display name
+// temp_policy: recursion, age, pattern and size rules for temp file cleaning
+// targets: custom cleanup locations, each registered as an operation
+// data_dir: where quarantine, journals and history are stored
+// quarantine: move deleted files into quarantine instead of removing them
+// optimal: settings profile applied unattended by 'optimal' and 'all'
+// health: thresholds that grade the findings reported by 'status'
+// metrics: listen address and refresh interval for 'serve-metrics'
+// api: listen address, authentication and TLS for 'serve'
+// schedules: recurring runs executed by 'daemon'
+// plans: named, ordered batches of operations run by 'plan run'
+// output: output format (text, json or yaml)
+// json_output: legacy switch equivalent to output: json
 type ConfigData struct {
-	DefaultOps []string                 `yaml:"default_ops"`
-	LogFile    string                   `yaml:"log_file"`
-	Timeout    time.Duration            `yaml:"timeout"`
-	Timeouts   map[string]time.Duration `yaml:"timeouts"`
-	JSONOutput bool                     `yaml:"json_output"`
+	DefaultOps []string                  `yaml:"default_ops"`
+	LogFile    string                    `yaml:"log_file"`
+	Timeout    time.Duration             `yaml:"timeout"`
+	Timeouts   map[string]time.Duration  `yaml:"timeouts"`
+	TempPolicy cleaner.CleanPolicy       `yaml:"temp_policy"`
+	Targets    []cleaner.Target          `yaml:"targets"`
+	DataDir    string                    `yaml:"data_dir"`
+	Quarantine bool                      `yaml:"quarantine"`
+	Optimal    *cleaner.OptimalProfile   `yaml:"optimal"`
+	Health     cleaner.HealthThresholds  `yaml:"health"`
+	History    cleaner.HistoryRetention  `yaml:"history"`
+	Metrics    MetricsConfig             `yaml:"metrics"`
+	API        APIConfig                 `yaml:"api"`
+	Schedules  []ScheduleConfig          `yaml:"schedules"`
+	Plans      []cleaner.MaintenancePlan `yaml:"plans"`
+	Output     string                    `yaml:"output"`
+	JSONOutput bool                      `yaml:"json_output"`
 }
 
 var (
 	// ConfigFile is the path to the YAML config; set via --config
 	ConfigFile string
 
-	// Config is populated by LoadConfig
-	Config ConfigData
+	// Config is populated by LoadConfig; settings the file omits keep these defaults
+	Config = ConfigData{Health: cleaner.DefaultHealthThresholds, History: cleaner.DefaultHistoryRetention, Metrics: DefaultMetricsConfig, API: DefaultAPIConfig}
 
 	// Logger is the global log target; set by SetupLogger
 	Logger *logrus.Logger
 
 	// Verbose toggles debug logging to console
 	Verbose bool
+
+	// DryRun reports what operations would do without making changes; set via --dry-run
+	DryRun bool
+
+	// Quarantine moves deleted files into the quarantine store; set via --quarantine or the config
+	Quarantine bool
+
+	// RunID identifies this invocation in the quarantine store and change journals
+	RunID = cleaner.NewRunID(time.Now())
 )
 
-// LoadConfig reads the YAML config (if present) into Config
-func LoadConfig() {
+// DataDir returns the directory for wincleaner's persistent data: data_dir from the
+// config, or a wincleaner folder in the user cache directory (%LocalAppData% on Windows)
+func DataDir() string {
+	if Config.DataDir != "" {
+		return cleaner.ExpandPath(Config.DataDir)
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "wincleaner")
+	}
+	return ".wincleaner"
+}
+
+// QuarantineNotice tells the user how to undo this run's deletions, if any were quarantined
+func QuarantineNotice() {
+	cli.quarantineNotice()
+}
+
+// JournalNotice tells the user how to undo this run's settings changes, if any were made
+func JournalNotice() {
+	cli.journalNotice()
+}
+
+// SaveHistory appends the status and operation results of this run to the history store.
+// A failure to write history is logged but does not fail the command.
+func SaveHistory() {
+	cli.saveHistory()
+}
+
+// HistoryDir returns the directory holding the status and run history
+func HistoryDir() string {
+	return filepath.Join(DataDir(), "history")
+}
+
+// JournalDir returns the directory holding settings change journals
+func JournalDir() string {
+	return filepath.Join(DataDir(), "journal")
+}
+
+// QuarantineDir returns the root of the quarantine store
+func QuarantineDir() string {
+	return filepath.Join(DataDir(), "quarantine")
+}
+
+// ConfigFileFromArgs finds the --config value in raw command-line arguments. The config
+// is loaded before cobra parses flags because custom targets become commands.
+func ConfigFileFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--config="); ok {
+			return value
+		}
+		if arg == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// ReservedCommands are the root command's built-in subcommands, besides the operations
+// themselves, including those cobra adds. A custom target may not take one of these IDs.
+var ReservedCommands = []string{
+	"all", "status", "auto", "optimal", "interactive", "admin", "restore", "quarantine",
+	"history", "serve-metrics", "serve", "daemon", "service", "plan", "help", "completion",
+}
+
+// LoadConfig reads the YAML config (if present) into Config and registers its custom targets.
+// An invalid section is reported and falls back to its default, but a target whose ID
+// cannot become a command of its own fails the load.
+func LoadConfig() error {
 	if ConfigFile == "" {
 		ConfigFile = "wincleaner.yaml"
 	}
 	data, err := os.ReadFile(ConfigFile)
 	if err != nil {
 		// No config file; silent fallback to defaults
-		return
+		return nil
 	}
+	// A file that does not parse may have been decoded partly; use none of it
+	defaults := Config
 	err = yaml.Unmarshal(data, &Config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to parse config file: %v\n", err)
+		Config = defaults
+		return nil
 	}
+
+	// Each section is checked on its own; an invalid one falls back to its default
+	// without keeping the sections after it from being validated
+	if err := Config.TempPolicy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid temp_policy in config file: %v\n", err)
+		Config.TempPolicy = defaults.TempPolicy
+	}
+	cleaner.TempPolicy = Config.TempPolicy
+
+	if Config.Optimal != nil {
+		if err := Config.Optimal.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid optimal profile in config file: %v\n", err)
+			Config.Optimal = nil
+		}
+	}
+
+	if err := Config.Health.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid health thresholds in config file: %v\n", err)
+		Config.Health = cleaner.DefaultHealthThresholds
+	}
+
+	if err := Config.History.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid history retention in config file: %v\n", err)
+		Config.History = cleaner.DefaultHistoryRetention
+	}
+
+	for _, target := range Config.Targets {
+		if target.ID != "" {
+			if err := cleaner.ValidateID(target.ID); err != nil {
+				return fmt.Errorf("invalid target in config file: %w", err)
+			}
+		}
+		if slices.Contains(ReservedCommands, target.ID) {
+			return fmt.Errorf("invalid target in config file: id %q clashes with the built-in %s command", target.ID, target.ID)
+		}
+		if err := target.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid target in config file: %v\n", err)
+			continue
+		}
+		if err := cleaner.RegisterOperation(target.Operation()); err != nil {
+			return fmt.Errorf("invalid target in config file: %w", err)
+		}
+	}
+
+	// Plans may use custom targets, so they are checked once those are registered
+	plans := Config.Plans[:0]
+	names := make(map[string]bool)
+	for _, plan := range Config.Plans {
+		err := plan.Validate()
+		if err == nil && names[plan.Name] {
+			err = fmt.Errorf("plan %q is defined more than once", plan.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid plan in config file: %v\n", err)
+			continue
+		}
+		names[plan.Name] = true
+		plans = append(plans, plan)
+	}
+	Config.Plans = plans
+	return nil
 }
 
 // SetupLogger initializes the structured logger with logrus and lumberjack for log rotation
@@ -87,108 +257,352 @@ func SetupLogger() {
 	Logger = logger
 }
 
-// Replace existing RunOperation with a unified context-aware runner supporting optional timeout
-func RunOperation(ctx context.Context, name string, operation func() error, timeout time.Duration) {
-	// apply config overrides for operation timeouts
+// cancelGrace is how long RunOperation waits for a canceled operation to stop its child processes
+const cancelGrace = 15 * time.Second
+
+// OperationFunc is the signature shared by every cleaner operation
+type OperationFunc func(ctx context.Context, opts cleaner.Options) (*cleaner.OperationResult, error)
+
+// RunOperation runs an operation with the options of the run in ctx, supporting an
+// optional timeout, records the structured result in the run report and returns it
+func RunOperation(ctx context.Context, name string, operation OperationFunc, timeout time.Duration) *cleaner.OperationResult {
+	res := runOperation(ctx, name, operation, configuredTimeout(name, timeout))
+	sessionFrom(ctx).record(res)
+	return res
+}
+
+// configuredTimeout applies the config's timeout for the operation name, or the global
+// timeout when the operation has none of its own
+func configuredTimeout(name string, timeout time.Duration) time.Duration {
 	if t, ok := Config.Timeouts[name]; ok {
-		timeout = t
-	} else if timeout == 0 && Config.Timeout > 0 {
-		timeout = Config.Timeout
+		return t
+	}
+	if timeout == 0 && Config.Timeout > 0 {
+		return Config.Timeout
 	}
+	return timeout
+}
+
+// runOperation runs an operation and waits for it to finish. When the timeout fires or ctx
+// is canceled (e.g. Ctrl-C) the operation's child processes are killed and the run is
+// recorded as timed out or canceled.
+func runOperation(ctx context.Context, name string, operation OperationFunc, timeout time.Duration) *cleaner.OperationResult {
 	if timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
+	s := sessionFrom(ctx)
+	opts := s.options()
+	w := s.console()
 	start := time.Now()
-	fmt.Printf("Running %s...\n", name)
-	Logger.Infof("Running %s...", name)
-	done := make(chan error, 1)
+	if opts.DryRun {
+		fmt.Fprintf(w, "Dry run of %s (no changes will be made)...\n", name)
+	} else {
+		fmt.Fprintf(w, "Running %s...\n", name)
+	}
+	Logger.WithField("dry_run", opts.DryRun).Infof("Running %s...", name)
+
+	type outcome struct {
+		res *cleaner.OperationResult
+		err error
+	}
+	done := make(chan outcome, 1)
 	go func() {
-		done <- operation()
+		res, err := operation(ctx, opts)
+		done <- outcome{res, err}
 	}()
 
+	// Only show elapsed-time progress for operations with a timeout
+	var tick <-chan time.Time
 	if timeout > 0 {
 		ticker := time.NewTicker(1 * time.Second)
 		defer ticker.Stop()
-		for {
+		tick = ticker.C
+	}
+	for {
+		select {
+		case out := <-done:
+			return reportResult(ctx, name, out.res, out.err)
+		case <-ctx.Done():
+			fmt.Fprintf(w, "\nOperation %s canceled: %v\n", name, ctx.Err())
+			Logger.Infof("Operation %s canceled: %v", name, ctx.Err())
 			select {
-			case err := <-done:
-				if err != nil {
-					fmt.Printf("Error running %s: %v\n", name, err)
-					Logger.Errorf("Error running %s: %v", name, err)
-				} else {
-					fmt.Printf("%s completed successfully.\n", name)
-					Logger.Infof("%s completed successfully.", name)
-				}
-				return
-			case <-ctx.Done():
-				fmt.Printf("\nOperation %s canceled: %v\n", name, ctx.Err())
-				Logger.Infof("Operation %s canceled: %v", name, ctx.Err())
-				return
-			case <-ticker.C:
-				elapsed := time.Since(start).Truncate(time.Second)
-				fmt.Printf("%s: %v elapsed...\r", name, elapsed)
+			case out := <-done:
+				return reportResult(ctx, name, out.res, out.err)
+			case <-time.After(cancelGrace):
+				// The operation ignored cancellation; record what we know and move on
+				Logger.Warnf("Operation %s did not stop within %v of cancellation", name, cancelGrace)
+				end := time.Now()
+				return reportResult(ctx, name, &cleaner.OperationResult{
+					DryRun:    opts.DryRun,
+					StartTime: start,
+					EndTime:   end,
+					Duration:  end.Sub(start),
+				}, ctx.Err())
 			}
+		case <-tick:
+			elapsed := time.Since(start).Truncate(time.Second)
+			fmt.Fprintf(w, "%s: %v elapsed...\r", name, elapsed)
 		}
 	}
+}
 
-	// No timeout: simple execution
-	err := <-done
+// reportResult prints and logs the outcome of an operation, filling in the
+// result's name, status and error. A done ctx marks the run as timed out or canceled.
+func reportResult(ctx context.Context, name string, res *cleaner.OperationResult, err error) *cleaner.OperationResult {
+	if res == nil {
+		res = &cleaner.OperationResult{}
+	}
+	w := sessionFrom(ctx).console()
+	if res.Name == "" {
+		res.Name = name
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		res.Status = cleaner.StatusCanceled
+		if ctxErr == context.DeadlineExceeded {
+			res.Status = cleaner.StatusTimeout
+		}
+		res.Error = ctxErr.Error()
+		Logger.WithField("status", res.Status).Infof("Operation %s stopped: %v", name, ctxErr)
+		return res
+	}
+	entry := Logger.WithFields(logrus.Fields{
+		"duration":       res.Duration.String(),
+		"bytes_freed":    res.BytesFreed,
+		"items_removed":  res.ItemsRemoved,
+		"items_restored": res.ItemsRestored,
+		"items_skipped":  len(res.ItemsSkipped),
+		"commands":       len(res.Commands),
+	})
 	if err != nil {
-		fmt.Printf("Error running %s: %v\n", name, err)
-		Logger.Errorf("Error running %s: %v", name, err)
-	} else {
-		fmt.Printf("%s completed successfully.\n", name)
-		Logger.Infof("%s completed successfully.", name)
+		res.Status = cleaner.StatusFailed
+		res.Error = err.Error()
+		fmt.Fprintf(w, "Error running %s: %v\n", name, err)
+		entry.Errorf("Error running %s: %v", name, err)
+		return res
+	}
+	res.Status = cleaner.StatusSuccess
+	switch {
+	case res.ItemsRestored > 0:
+		verb := "Restored"
+		if res.DryRun {
+			verb = "Would restore"
+		}
+		fmt.Fprintf(w, "%s %d items (%s), skipped %d.\n", verb, res.ItemsRestored, cleaner.FormatBytes(float64(res.BytesRestored)), len(res.ItemsSkipped))
+	case res.ItemsRemoved > 0 || len(res.ItemsSkipped) > 0:
+		verb := "Removed"
+		if res.DryRun {
+			verb = "Would remove"
+		}
+		fmt.Fprintf(w, "%s %d items (%s), skipped %d.\n", verb, res.ItemsRemoved, cleaner.FormatBytes(float64(res.BytesFreed)), len(res.ItemsSkipped))
+	}
+	fmt.Fprintf(w, "%s completed successfully.\n", name)
+	if res.RebootRequired {
+		fmt.Fprintln(w, "A restart is required for the changes to take effect.")
+	}
+	entry.Infof("%s completed successfully.", name)
+	return res
+}
+
+// RunRegistered runs a registered cleaner operation, honoring ID-keyed timeout overrides.
+// Operations that require administrator rights are skipped when not elevated, except in dry-run mode.
+func RunRegistered(ctx context.Context, op cleaner.Operation) *cleaner.OperationResult {
+	return runRegistered(ctx, op, registeredTimeout(op, false))
+}
+
+// registeredTimeout returns the timeout for a registered operation: its default, or
+// within 'all' its AllTimeout, unless the config overrides it by ID or name
+func registeredTimeout(op cleaner.Operation, all bool) time.Duration {
+	timeout := op.Timeout
+	if all && op.AllTimeout > 0 {
+		timeout = op.AllTimeout
 	}
+	if t, ok := Config.Timeouts[op.ID]; ok {
+		timeout = t
+	}
+	return configuredTimeout(op.Name, timeout)
+}
+
+// RunRegisteredTimeout is RunRegistered with a timeout that replaces the operation's
+// default and configured ones, as set on a plan step
+func RunRegisteredTimeout(ctx context.Context, op cleaner.Operation, timeout time.Duration) *cleaner.OperationResult {
+	return runRegistered(ctx, op, timeout)
+}
+
+func runRegistered(ctx context.Context, op cleaner.Operation, timeout time.Duration) *cleaner.OperationResult {
+	s := sessionFrom(ctx)
+	if op.RequiresAdmin && !s.report.DryRun && !cleaner.IsAdmin() {
+		fmt.Fprintf(s.console(), "Skipping %s: administrator privileges required.\n", op.Name)
+		Logger.Warnf("Skipping %s: administrator privileges required", op.Name)
+		now := time.Now()
+		res := &cleaner.OperationResult{
+			Operation: op.ID,
+			Name:      op.Name,
+			Status:    cleaner.StatusNeedsAdmin,
+			StartTime: now,
+			EndTime:   now,
+			Error:     "administrator privileges required",
+		}
+		s.record(res)
+		return res
+	}
+	res := runOperation(ctx, op.Name, op.Execute, timeout)
+	s.record(res)
+	res.Operation = op.ID
+	return res
 }
 
-// Simplify RunAllOperations to use unified RunOperation
-func RunAllOperations(ctx context.Context) {
-	fmt.Println("Running all cleaning operations...")
+// RunAllOperations runs every registered operation in order, followed by the optimal
+// profile from the config if there is one, and returns their results
+func RunAllOperations(ctx context.Context) []*cleaner.OperationResult {
+	w := sessionFrom(ctx).console()
+	fmt.Fprintln(w, "Running all cleaning operations...")
 	Logger.Info("Running all cleaning operations...")
-	ops := []struct {
-		name    string
-		op      func() error
-		timeout time.Duration
-	}{
-		{"Disk Cleanup", func() error { return cleaner.RunDiskCleanup(Verbose) }, 0},
-		{"Temporary Files Cleaning", func() error { return cleaner.CleanTempFiles(Verbose) }, 0},
-		{"Event Logs Clearing", func() error { return cleaner.ClearEventLogs(Verbose) }, 0},
-		{"System File Checker", func() error { return cleaner.RunSystemFileChecker(Verbose) }, 120 * time.Second},
-		{"DISM Windows Image Repair", func() error { return cleaner.RunDISM(Verbose) }, 180 * time.Second},
-		{"Empty Recycle Bin", func() error { return cleaner.EmptyRecycleBin(Verbose) }, 0},
-		{"Disk Optimization", func() error { return cleaner.RunDiskOptimization(Verbose) }, 0},
-		{"Check Disk", func() error { return cleaner.RunCheckDisk(Verbose) }, 90 * time.Second},
-		{"Flush DNS Cache", func() error { return cleaner.FlushDNSCache(Verbose) }, 0},
-		{"Windows Memory Diagnostic", func() error { return cleaner.RunMemoryDiagnostic(Verbose) }, 0},
-		{"Clean Prefetch Cache", func() error { return cleaner.CleanPrefetch(Verbose) }, 0},
-		{"Optimize Power Configuration", func() error { return cleaner.OptimizePowerConfig(Verbose) }, 0},
-		{"Reset Network Configuration", func() error { return cleaner.ResetNetworkConfig(Verbose) }, 0},
-	}
-	for _, op := range ops {
-		RunOperation(ctx, op.name, op.op, op.timeout)
-	}
-	fmt.Println("All cleaning operations completed.")
+	var results []*cleaner.OperationResult
+	for _, op := range cleaner.Operations() {
+		results = append(results, runRegistered(ctx, op, registeredTimeout(op, true)))
+	}
+	if Config.Optimal != nil {
+		results = append(results, ApplyOptimalProfile(ctx, *Config.Optimal))
+	}
+	fmt.Fprintln(w, "All cleaning operations completed.")
 	Logger.Info("All cleaning operations completed.")
+	return results
+}
+
+// ApplyOptimalProfile applies an optimal settings profile unattended
+func ApplyOptimalProfile(ctx context.Context, profile cleaner.OptimalProfile) *cleaner.OperationResult {
+	res := RunOperation(ctx, "Apply Optimal Settings Profile", func(ctx context.Context, opts cleaner.Options) (*cleaner.OperationResult, error) {
+		return cleaner.ApplyOptimalProfile(ctx, opts, profile)
+	}, 0)
+	res.Operation = "optimal"
+	return res
 }
 
-// DisplaySystemStatus formats and prints the system status info
+// DisplaySystemStatus formats and prints the system status info as text
 func DisplaySystemStatus(status *cleaner.SystemStatus) {
-	// JSON output mode
-	if Config.JSONOutput {
-		fmt.Println("\n=== System Status Information ===")
-		fmt.Printf("Windows Version: %s\n", status.WindowsVersion)
-		fmt.Printf("Last Boot Time: %s\n", status.LastBootTime)
-		fmt.Println("\nDisk Space Information:")
-		fmt.Println("------------------------")
-		for drive, info := range status.DiskSpace {
-			fmt.Printf("Drive %s:\n", drive)
-			fmt.Printf("  Total Size: %s\n", info.TotalSize)
-			fmt.Printf("  Free Space: %s\n", info.FreeSpace)
-			fmt.Printf("  Used Space: %s (%s)\n", info.UsedSpace, info.UsedPercent)
-			fmt.Println()
+	w := Console()
+	fmt.Fprintln(w, "\n=== System Status Information ===")
+	fmt.Fprintf(w, "Windows Version: %s\n", status.WindowsVersion)
+	if status.LastBootTime.IsZero() {
+		fmt.Fprintln(w, "Last Boot Time: unknown")
+	} else {
+		fmt.Fprintf(w, "Last Boot Time: %s\n", status.LastBootTime.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "Uptime: %s\n", FormatUptime(status.Uptime))
+	}
+	if status.CPULoadPercent != nil {
+		fmt.Fprintf(w, "CPU Load: %.1f%%\n", *status.CPULoadPercent)
+	}
+	if m := status.Memory; m != nil {
+		fmt.Fprintf(w, "Memory: %s of %s used (%.1f%%)\n", cleaner.FormatBytes(float64(m.TotalBytes-m.AvailableBytes)), cleaner.FormatBytes(float64(m.TotalBytes)), m.UsedPercent)
+		fmt.Fprintf(w, "Commit Charge: %s of %s (%.1f%%)\n", cleaner.FormatBytes(float64(m.CommitUsedBytes)), cleaner.FormatBytes(float64(m.CommitLimitBytes)), m.CommitPercent)
+	}
+	if status.TempFolderBytes != nil {
+		fmt.Fprintf(w, "Temporary Files: %s\n", cleaner.FormatBytes(float64(*status.TempFolderBytes)))
+	}
+	for _, pf := range status.PageFiles {
+		fmt.Fprintf(w, "Page File %s: %s of %s used\n", pf.Path, cleaner.FormatBytes(float64(pf.UsedBytes)), cleaner.FormatBytes(float64(pf.TotalBytes)))
+	}
+	if r := status.PendingReboot; r != nil {
+		if r.Required() {
+			var reasons []string
+			if r.ComponentServicing {
+				reasons = append(reasons, "component servicing")
+			}
+			if r.WindowsUpdate {
+				reasons = append(reasons, "Windows Update")
+			}
+			if r.FileRenames {
+				reasons = append(reasons, "pending file renames")
+			}
+			fmt.Fprintf(w, "Pending Reboot: yes (%s)\n", strings.Join(reasons, ", "))
+		} else {
+			fmt.Fprintln(w, "Pending Reboot: no")
+		}
+	}
+	if d := status.DNS; d != nil {
+		if d.Resolved {
+			fmt.Fprintf(w, "DNS: %s resolves\n", d.Host)
+		} else {
+			fmt.Fprintf(w, "DNS: cannot resolve %s (%s)\n", d.Host, d.Error)
+		}
+	}
+
+	fmt.Fprintln(w, "\nDisk Space Information:")
+	fmt.Fprintln(w, "------------------------")
+	for _, drive := range status.Drives() {
+		info := status.DiskSpace[drive]
+		fmt.Fprintf(w, "Drive %s", drive)
+		if info.FileSystem != "" || info.DriveType != "" {
+			fmt.Fprintf(w, " (%s)", strings.TrimSpace(info.DriveType+" "+info.FileSystem))
+		}
+		fmt.Fprintln(w, ":")
+		fmt.Fprintf(w, "  Total Size: %s\n", cleaner.FormatBytes(float64(info.TotalBytes)))
+		fmt.Fprintf(w, "  Free Space: %s\n", cleaner.FormatBytes(float64(info.FreeBytes)))
+		fmt.Fprintf(w, "  Used Space: %s (%.1f%%)\n", cleaner.FormatBytes(float64(info.UsedBytes)), info.UsedPercent)
+		fmt.Fprintln(w)
+	}
+
+	if len(status.StoppedServices) > 0 {
+		fmt.Fprintln(w, "Stopped Automatic Services:")
+		fmt.Fprintln(w, "------------------------")
+		for _, svc := range status.StoppedServices {
+			delayed := ""
+			if svc.DelayedStart {
+				delayed = " (delayed start)"
+			}
+			fmt.Fprintf(w, "  %s - %s%s\n", svc.Name, svc.DisplayName, delayed)
 		}
+		fmt.Fprintln(w)
+	}
+
+	if len(status.Errors) > 0 {
+		fmt.Fprintln(w, "Unavailable Information:")
+		names := make([]string, 0, len(status.Errors))
+		for name := range status.Errors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %s\n", name, status.Errors[name])
+		}
+	}
+}
+
+// DisplayHealth prints the health score and any findings that are not OK, each with
+// the operation or action that addresses it
+func DisplayHealth(health *cleaner.HealthReport) {
+	w := Console()
+	fmt.Fprintln(w, "\n=== System Health ===")
+	fmt.Fprintf(w, "Health Score: %d/100 (%s)\n", health.Score, strings.ToUpper(health.Verdict))
+	problems := health.Problems()
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "No problems found.")
+		return
+	}
+	for _, f := range problems {
+		check := f.Check
+		if f.Subject != "" {
+			check += " " + f.Subject
+		}
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", strings.ToUpper(f.Severity), check, f.Message)
+		if f.Operation != "" {
+			fmt.Fprintf(w, "       Run: wincleaner %s\n", f.Operation)
+		}
+		if f.Advice != "" {
+			fmt.Fprintf(w, "       %s\n", f.Advice)
+		}
+	}
+}
+
+// FormatUptime formats an uptime as days, hours and minutes
+func FormatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
 	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
 }
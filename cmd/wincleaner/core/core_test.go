@@ -0,0 +1,124 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+func TestRegisteredTimeout(t *testing.T) {
+	sfc, ok := cleaner.LookupOperation("sfc")
+	if !ok {
+		t.Fatal("sfc is not registered")
+	}
+	flush, _ := cleaner.LookupOperation("flushdns")
+
+	tests := []struct {
+		name     string
+		op       cleaner.Operation
+		all      bool
+		timeout  time.Duration
+		timeouts map[string]time.Duration
+		want     time.Duration
+	}{
+		{name: "default", op: sfc, want: 1000 * time.Second},
+		{name: "default within all", op: sfc, all: true, want: 120 * time.Second},
+		{name: "override by ID", op: sfc, all: true, timeouts: map[string]time.Duration{"sfc": time.Hour}, want: time.Hour},
+		{name: "override by name", op: sfc, all: true,
+			timeouts: map[string]time.Duration{"sfc": time.Hour, "System File Checker": time.Minute}, want: time.Minute},
+		{name: "no default", op: flush, all: true},
+		{name: "global timeout", op: flush, all: true, timeout: 2 * time.Minute, want: 2 * time.Minute},
+		{name: "global timeout leaves defaults alone", op: sfc, all: true, timeout: 2 * time.Minute, want: 120 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := Config
+			t.Cleanup(func() { Config = saved })
+			Config.Timeout, Config.Timeouts = tt.timeout, tt.timeouts
+
+			if got := registeredTimeout(tt.op, tt.all); got != tt.want {
+				t.Errorf("timeout %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckService(t *testing.T) {
+	nightly := ScheduleConfig{Name: "nightly", Cron: "@daily", Operations: []string{"temp"}}
+	idle := nightly
+	idle.Name, idle.OnlyWhenIdle = "idle", 15*time.Minute
+
+	tests := []struct {
+		name      string
+		schedules []ScheduleConfig
+		wantErr   string
+	}{
+		{name: "schedules", schedules: []ScheduleConfig{nightly}},
+		{name: "only_when_idle", schedules: []ScheduleConfig{nightly, idle}, wantErr: "only_when_idle"},
+		{name: "nothing to run", wantErr: "nothing to run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := Config
+			t.Cleanup(func() { Config = saved })
+			Config.Schedules, Config.API = tt.schedules, APIConfig{}
+			t.Setenv(APITokenEnv, "")
+
+			err := CheckService()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("error %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// A target becomes a command, so an ID that cannot be one fails the whole config
+func TestLoadConfigTargetIDs(t *testing.T) {
+	useTestEnvironment(t)
+	savedFile := ConfigFile
+	t.Cleanup(func() { ConfigFile = savedFile })
+
+	tests := []struct {
+		name    string
+		id      string
+		wantErr string
+	}{
+		{name: "built-in command", id: "status", wantErr: "clashes with the built-in status command"},
+		{name: "cobra command", id: "help", wantErr: "clashes with the built-in help command"},
+		{name: "operation", id: "disk", wantErr: "already registered"},
+		{name: "space", id: "my cache", wantErr: "may only contain"},
+		{name: "slash", id: "cache/dir", wantErr: "may only contain"},
+		{name: "uppercase", id: "Cache", wantErr: "may only contain"},
+		// A well-formed target with another problem is only skipped
+		{name: "missing path", id: "load-config-test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ConfigFile = filepath.Join(t.TempDir(), "wincleaner.yaml")
+			config := "targets:\n  - id: \"" + tt.id + "\"\n"
+			if tt.wantErr != "" {
+				config += "    path: cache\n"
+			}
+			if err := os.WriteFile(ConfigFile, []byte(config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := LoadConfig()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+	"github.com/user/windows_health/pkg/schedule"
+)
+
+// defaultMaxDelay is how long a scheduled run waits for its conditions by default
+const defaultMaxDelay = time.Hour
+
+// conditionPoll is how often a waiting run rechecks its conditions, and the longest the
+// scheduler sleeps at once, so that clock changes and system sleep are noticed
+const conditionPoll = time.Minute
+
+// ScheduleConfig is one entry of the `schedules` config section
+type ScheduleConfig struct {
+	Name string `yaml:"name"`
+	// Cron is a five-field cron expression or a macro such as @daily, in local time
+	Cron       string   `yaml:"cron"`
+	Operations []string `yaml:"operations"`
+	// Plan runs a maintenance plan from the `plans` section instead of Operations
+	Plan string `yaml:"plan"`
+	// QuietHours (HH:MM-HH:MM) is a daily window in which the schedule does not start
+	QuietHours string `yaml:"quiet_hours"`
+	// OnlyWhenIdle requires this long without keyboard or mouse input
+	OnlyWhenIdle time.Duration `yaml:"only_when_idle"`
+	// OnACPower requires the system not to be running on battery
+	OnACPower bool `yaml:"on_ac_power"`
+	// MaxDelay is how long a due run waits for quiet hours to end and the conditions to
+	// hold before it is skipped (default 1h)
+	MaxDelay time.Duration `yaml:"max_delay"`
+	DryRun   bool          `yaml:"dry_run"`
+}
+
+// scheduledJob is a validated schedule
+type scheduledJob struct {
+	ScheduleConfig
+	cron  *schedule.Cron
+	quiet *schedule.QuietHours
+}
+
+// compileSchedules validates the configured schedules. It runs when the daemon starts,
+// after custom targets have been registered as operations.
+func compileSchedules(configs []ScheduleConfig) ([]*scheduledJob, error) {
+	names := make(map[string]bool)
+	var jobs []*scheduledJob
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("schedule-%d", i+1)
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("schedule %s: duplicate name", cfg.Name)
+		}
+		names[cfg.Name] = true
+
+		cron, err := schedule.ParseCron(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", cfg.Name, err)
+		}
+		quiet, err := schedule.ParseQuietHours(cfg.QuietHours)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", cfg.Name, err)
+		}
+		switch {
+		case len(cfg.Operations) == 0 && cfg.Plan == "":
+			return nil, fmt.Errorf("schedule %s: no operations or plan", cfg.Name)
+		case len(cfg.Operations) > 0 && cfg.Plan != "":
+			return nil, fmt.Errorf("schedule %s: give either operations or a plan, not both", cfg.Name)
+		case cfg.Plan != "":
+			if _, ok := FindPlan(cfg.Plan); !ok {
+				return nil, fmt.Errorf("schedule %s: unknown plan %q", cfg.Name, cfg.Plan)
+			}
+		}
+		for _, id := range cfg.Operations {
+			if _, ok := cleaner.LookupOperation(id); !ok {
+				return nil, fmt.Errorf("schedule %s: unknown operation %q", cfg.Name, id)
+			}
+		}
+		if cfg.MaxDelay <= 0 {
+			cfg.MaxDelay = defaultMaxDelay
+		}
+		jobs = append(jobs, &scheduledJob{ScheduleConfig: cfg, cron: cron, quiet: quiet})
+	}
+	return jobs, nil
+}
+
+// ScheduledRun is the next run of a schedule
+type ScheduledRun struct {
+	Schedule string    `json:"schedule" yaml:"schedule"`
+	Cron     string    `json:"cron" yaml:"cron"`
+	Next     time.Time `json:"next" yaml:"next"`
+}
+
+// NextRuns validates the configured schedules and returns when each runs next
+func NextRuns(now time.Time) ([]ScheduledRun, error) {
+	jobs, err := compileSchedules(Config.Schedules)
+	if err != nil {
+		return nil, err
+	}
+	var runs []ScheduledRun
+	for _, job := range jobs {
+		runs = append(runs, ScheduledRun{Schedule: job.Name, Cron: job.Cron, Next: job.cron.Next(now)})
+	}
+	return runs, nil
+}
+
+// RunDaemon runs the configured schedules, and the REST API when authentication for it
+// is configured, until ctx is canceled or the API server fails. It is the body of both
+// the 'daemon' command and the Windows service.
+//
+// Each schedule waits for its next due time, then for quiet hours to end and its
+// conditions to hold, and runs its operations in a session. A schedule never overlaps
+// itself: the next due time is computed after a run finishes, so occurrences missed
+// while running are skipped. Runs of different schedules, and API jobs, are serialized.
+func RunDaemon(ctx context.Context) error {
+	jobs, serveAPI, err := daemonPlan()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The flag is read here, once, rather than by the schedules' goroutines
+	dryRun := DryRun
+	out := Console()
+	var wg sync.WaitGroup
+	if len(jobs) > 0 {
+		fmt.Fprintf(out, "Scheduler started with %d schedules. Press Ctrl-C to stop.\n", len(jobs))
+		Logger.Infof("Scheduler started with %d schedules", len(jobs))
+		for _, job := range jobs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runSchedule(ctx, job, dryRun, out)
+			}()
+		}
+	}
+
+	var apiErr error
+	if serveAPI {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if apiErr = ServeAPI(ctx, Config.API); apiErr != nil {
+				Logger.Errorf("API server failed: %v", apiErr)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(jobs) > 0 {
+		Logger.Info("Scheduler stopped")
+	}
+	return apiErr
+}
+
+// CheckDaemon validates the configuration RunDaemon would use
+func CheckDaemon() error {
+	_, _, err := daemonPlan()
+	return err
+}
+
+// daemonPlan returns the validated schedules and whether to serve the API
+func daemonPlan() ([]*scheduledJob, bool, error) {
+	jobs, err := compileSchedules(Config.Schedules)
+	if err != nil {
+		return nil, false, err
+	}
+	serveAPI := APIConfigured(Config.API)
+	if len(jobs) == 0 && !serveAPI {
+		return nil, false, fmt.Errorf("nothing to run: configure schedules, or api.token (or %s) or api.client_ca for the API", APITokenEnv)
+	}
+	return jobs, serveAPI, nil
+}
+
+// runSchedule is the loop of one schedule. dryRun is the daemon's --dry-run flag, which
+// makes every run a dry run.
+func runSchedule(ctx context.Context, job *scheduledJob, dryRun bool, out io.Writer) {
+	for {
+		next := job.cron.Next(time.Now())
+		if next.IsZero() {
+			Logger.Errorf("Schedule %s: %q never matches", job.Name, job.Cron)
+			return
+		}
+		fmt.Fprintf(out, "Schedule %s: next run at %s\n", job.Name, next.Format("2006-01-02 15:04"))
+		Logger.Infof("Schedule %s: next run at %s", job.Name, next.Format(time.RFC3339))
+		if sleepUntil(ctx, next) != nil {
+			return
+		}
+
+		ready, reason := waitForConditions(ctx, job, next.Add(job.MaxDelay))
+		if ctx.Err() != nil {
+			return
+		}
+		if !ready {
+			fmt.Fprintf(out, "Schedule %s: skipped the %s run: %s\n", job.Name, next.Format("15:04"), reason)
+			Logger.Warnf("Schedule %s: skipped run due at %s: %s", job.Name, next.Format(time.RFC3339), reason)
+			continue
+		}
+
+		what := fmt.Sprint(job.Operations)
+		if job.Plan != "" {
+			what = "plan " + job.Plan
+		}
+		fmt.Fprintf(out, "Schedule %s: running %s\n", job.Name, what)
+		report := RunSession(ctx, "schedule "+job.Name, job.DryRun || dryRun, out, func(ctx context.Context) {
+			if plan, ok := FindPlan(job.Plan); ok {
+				RunPlan(ctx, plan)
+				return
+			}
+			for _, id := range job.Operations {
+				if ctx.Err() != nil {
+					return
+				}
+				op, _ := cleaner.LookupOperation(id)
+				RunRegistered(ctx, op)
+			}
+		})
+		if report.Failed() {
+			Logger.Warnf("Schedule %s: run %s finished with failures", job.Name, report.RunID)
+		} else {
+			Logger.Infof("Schedule %s: run %s finished", job.Name, report.RunID)
+		}
+	}
+}
+
+// sleepUntil waits until t in steps of at most conditionPoll, so a wall-clock jump or
+// a resume from sleep is noticed
+func sleepUntil(ctx context.Context, t time.Time) error {
+	for {
+		wait := time.Until(t)
+		if wait <= 0 {
+			return nil
+		}
+		if err := cleaner.SleepContext(ctx, min(wait, conditionPoll)); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForConditions polls until the schedule may run or the deadline passes, returning
+// the last reason it could not run
+func waitForConditions(ctx context.Context, job *scheduledJob, deadline time.Time) (bool, string) {
+	for {
+		reason := blockedReason(ctx, job, time.Now())
+		if reason == "" {
+			return true, ""
+		}
+		if !time.Now().Add(conditionPoll).Before(deadline) {
+			return false, reason
+		}
+		Logger.Debugf("Schedule %s: waiting: %s", job.Name, reason)
+		if cleaner.SleepContext(ctx, conditionPoll) != nil {
+			return false, "stopped"
+		}
+	}
+}
+
+// blockedReason explains why the schedule may not start now, or returns "" if it may.
+// Conditions the platform cannot check are treated as met.
+func blockedReason(ctx context.Context, job *scheduledJob, now time.Time) string {
+	if job.quiet.Contains(now) {
+		return fmt.Sprintf("quiet hours until %s", job.quiet.EndAfter(now).Format("15:04"))
+	}
+	if job.OnACPower {
+		onAC, err := cleaner.SystemInfo.OnACPower(ctx)
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+		case err != nil:
+			return fmt.Sprintf("cannot read power status: %v", err)
+		case !onAC:
+			return "running on battery"
+		}
+	}
+	if job.OnlyWhenIdle > 0 {
+		idle, err := cleaner.SystemInfo.IdleTime(ctx)
+		switch {
+		case errors.Is(err, errors.ErrUnsupported):
+		case err != nil:
+			return fmt.Sprintf("cannot read idle time: %v", err)
+		case idle < job.OnlyWhenIdle:
+			return fmt.Sprintf("idle for %s, needs %s", idle.Truncate(time.Second), job.OnlyWhenIdle)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// Process exit codes. Code 1 is reserved for usage and configuration errors reported by cobra.
+const (
+	ExitSuccess        = 0 // every operation succeeded
+	ExitPartialFailure = 2 // some operations failed
+	ExitTotalFailure   = 3 // every operation failed, or the command itself failed
+	ExitTimeout        = 4 // at least one operation timed out or was canceled
+	ExitNeedsAdmin     = 5 // at least one operation was skipped for lack of administrator rights
+	ExitNeedsReboot    = 6 // everything succeeded but a restart is required to finish
+)
+
+// ExitCode derives the process exit code from the results recorded in the run report.
+// Needs-admin takes precedence over timeouts, which take precedence over plain failures.
+func ExitCode() int {
+	return exitCodeFor(Report.Operations, len(Report.Errors))
+}
+
+// Failed reports whether any operation in the report, or the command itself, failed
+func (r RunReport) Failed() bool {
+	code := exitCodeFor(r.Operations, len(r.Errors))
+	return code != ExitSuccess && code != ExitNeedsReboot
+}
+
+func exitCodeFor(results []*cleaner.OperationResult, commandErrors int) int {
+	var failed, timedOut, needsAdmin int
+	reboot := false
+	for _, res := range results {
+		switch res.Status {
+		case cleaner.StatusSuccess:
+		case cleaner.StatusTimeout, cleaner.StatusCanceled:
+			timedOut++
+			failed++
+		case cleaner.StatusNeedsAdmin:
+			needsAdmin++
+			failed++
+		default:
+			failed++
+		}
+		reboot = reboot || res.RebootRequired
+	}
+
+	switch {
+	case failed == 0 && commandErrors == 0:
+		if reboot {
+			return ExitNeedsReboot
+		}
+		return ExitSuccess
+	case needsAdmin > 0:
+		return ExitNeedsAdmin
+	case timedOut > 0:
+		return ExitTimeout
+	case failed == len(results):
+		return ExitTotalFailure
+	default:
+		return ExitPartialFailure
+	}
+}
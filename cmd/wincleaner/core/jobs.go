@@ -0,0 +1,319 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// maxFinishedJobs is how many finished jobs a JobManager remembers
+const maxFinishedJobs = 100
+
+// maxJobLog is how much of a job's output is kept. Older output is dropped, a chunk
+// at a time, once the log grows a quarter past it.
+const maxJobLog = 1 << 20
+
+// ErrJobFinished is returned when canceling a job that has already finished
+var ErrJobFinished = errors.New("job has already finished")
+
+// Job is a batch of operations started through the API
+type Job struct {
+	ID         string                     `json:"id"`
+	Operations []string                   `json:"operations"`
+	DryRun     bool                       `json:"dry_run"`
+	State      string                     `json:"state"`
+	RunID      string                     `json:"run_id,omitempty"`
+	Created    time.Time                  `json:"created"`
+	Started    *time.Time                 `json:"started,omitempty"`
+	Finished   *time.Time                 `json:"finished,omitempty"`
+	Results    []*cleaner.OperationResult `json:"results"`
+	Error      string                     `json:"error,omitempty"`
+
+	log    *JobLog
+	cancel context.CancelFunc
+}
+
+// finished reports whether the job has reached a final state
+func (j *Job) finished() bool {
+	return j.State == JobSucceeded || j.State == JobFailed || j.State == JobCanceled
+}
+
+// JobLog is the console output of a job. Readers can follow it while the job runs.
+type JobLog struct {
+	mu   sync.Mutex
+	data []byte
+	// dropped counts the bytes discarded from the start of the log
+	dropped int
+	closed  bool
+	changed chan struct{}
+}
+
+// newJobLog returns an empty, open log
+func newJobLog() *JobLog {
+	return &JobLog{changed: make(chan struct{})}
+}
+
+// Write implements io.Writer
+func (l *JobLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.data = append(l.data, p...)
+	if len(l.data) > maxJobLog+maxJobLog/4 {
+		cut := len(l.data) - maxJobLog
+		l.dropped += cut
+		l.data = append([]byte(nil), l.data[cut:]...)
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return len(p), nil
+}
+
+// close marks the log complete and wakes any followers
+func (l *JobLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		close(l.changed)
+	}
+}
+
+// ReadFrom returns the log after offset, the offset to read from next, whether the log
+// is complete, and a channel that is closed when more output arrives. If output after
+// offset has been dropped, a note saying how much replaces it.
+func (l *JobLog) ReadFrom(offset int) ([]byte, int, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var data []byte
+	if offset < l.dropped {
+		data = fmt.Appendf(nil, "[%d bytes of earlier output were dropped]\n", l.dropped-offset)
+		offset = l.dropped
+	}
+	end := l.dropped + len(l.data)
+	offset = min(offset, end)
+	return append(data, l.data[offset-l.dropped:]...), end, l.closed, l.changed
+}
+
+// JobManager queues jobs and runs them one at a time, each in its own session
+type JobManager struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string
+	queue chan *Job
+	// dryRun is the server's --dry-run flag, which makes every job a dry run
+	dryRun bool
+}
+
+// NewJobManager starts a manager whose worker runs until ctx is canceled; canceling
+// ctx also cancels the running job
+func NewJobManager(ctx context.Context) *JobManager {
+	m := &JobManager{jobs: make(map[string]*Job), queue: make(chan *Job, maxFinishedJobs), dryRun: DryRun}
+	go m.work(ctx)
+	return m
+}
+
+// Start validates the operation IDs and queues a job to run them in order. The job is a
+// dry run if dryRun is set or the server runs with --dry-run.
+func (m *JobManager) Start(ids []string, dryRun bool) (*Job, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("no operations given")
+	}
+	for _, id := range ids {
+		if _, ok := cleaner.LookupOperation(id); !ok {
+			return nil, fmt.Errorf("unknown operation %q", id)
+		}
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, fmt.Errorf("generating job ID: %w", err)
+	}
+	job := &Job{
+		ID:         id,
+		Operations: ids,
+		DryRun:     dryRun || m.dryRun,
+		State:      JobQueued,
+		Created:    time.Now(),
+		Results:    []*cleaner.OperationResult{},
+		log:        newJobLog(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case m.queue <- job:
+	default:
+		return nil, errors.New("too many queued jobs")
+	}
+	m.jobs[job.ID] = job
+	m.order = append(m.order, job.ID)
+	m.prune()
+	Logger.Infof("Queued job %s: %v (dry run: %v)", job.ID, ids, dryRun)
+	return m.snapshot(job), nil
+}
+
+// Get returns a copy of a job
+func (m *JobManager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return m.snapshot(job), true
+}
+
+// List returns copies of all remembered jobs, oldest first
+func (m *JobManager) List() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]*Job, 0, len(m.order))
+	for _, id := range m.order {
+		jobs = append(jobs, m.snapshot(m.jobs[id]))
+	}
+	return jobs
+}
+
+// Log returns a job's log
+func (m *JobManager) Log(id string) (*JobLog, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return job.log, true
+}
+
+// Cancel stops a running job, or drops a queued one before it starts
+func (m *JobManager) Cancel(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	switch {
+	case job.finished():
+		return m.snapshot(job), ErrJobFinished
+	case job.State == JobQueued:
+		now := time.Now()
+		job.State, job.Finished = JobCanceled, &now
+		job.log.close()
+		m.prune()
+	case job.cancel != nil:
+		job.cancel()
+	}
+	Logger.Infof("Canceled job %s", id)
+	return m.snapshot(job), nil
+}
+
+// work runs queued jobs until ctx is canceled
+func (m *JobManager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-m.queue:
+			m.run(ctx, job)
+		}
+	}
+}
+
+// run executes one job in a session
+func (m *JobManager) run(ctx context.Context, job *Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m.mu.Lock()
+	if job.State != JobQueued {
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	job.State, job.Started, job.cancel = JobRunning, &now, cancel
+	m.mu.Unlock()
+
+	report := RunSession(jobCtx, "job "+job.ID, job.DryRun, job.log, func(jobCtx context.Context) {
+		m.mu.Lock()
+		job.RunID = sessionFrom(jobCtx).report.RunID
+		m.mu.Unlock()
+		for _, id := range job.Operations {
+			if jobCtx.Err() != nil {
+				break
+			}
+			op, _ := cleaner.LookupOperation(id)
+			res := RunRegistered(jobCtx, op)
+			m.mu.Lock()
+			job.Results = append(job.Results, res)
+			m.mu.Unlock()
+		}
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	finished := time.Now()
+	job.Finished, job.cancel = &finished, nil
+	switch {
+	case jobCtx.Err() != nil:
+		job.State, job.Error = JobCanceled, jobCtx.Err().Error()
+	case report.Failed():
+		job.State = JobFailed
+	default:
+		job.State = JobSucceeded
+	}
+	job.log.close()
+	m.prune()
+	Logger.Infof("Job %s %s", job.ID, job.State)
+}
+
+// snapshot copies a job so callers can read it without holding the lock
+func (m *JobManager) snapshot(job *Job) *Job {
+	copied := *job
+	copied.Operations = append([]string(nil), job.Operations...)
+	copied.Results = append([]*cleaner.OperationResult{}, job.Results...)
+	return &copied
+}
+
+// prune forgets the oldest finished jobs beyond maxFinishedJobs
+func (m *JobManager) prune() {
+	finished := 0
+	for _, id := range m.order {
+		if m.jobs[id].finished() {
+			finished++
+		}
+	}
+	kept := m.order[:0]
+	for _, id := range m.order {
+		if finished > maxFinishedJobs && m.jobs[id].finished() {
+			delete(m.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
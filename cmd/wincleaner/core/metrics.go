@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+	"github.com/user/windows_health/pkg/metrics"
+)
+
+// MetricsConfig configures the 'serve-metrics' exporter
+type MetricsConfig struct {
+	// Listen is the address of the HTTP server, e.g. ":9182" or "127.0.0.1:9182"
+	Listen string `yaml:"listen"`
+	// Interval is how often the system status is collected
+	Interval time.Duration `yaml:"interval"`
+}
+
+// DefaultMetricsConfig is used for settings the config file does not override
+var DefaultMetricsConfig = MetricsConfig{Listen: ":9182", Interval: time.Minute}
+
+// CollectMetrics gathers the system status, its health evaluation and the operation
+// history into a metrics snapshot
+func CollectMetrics(ctx context.Context) (metrics.Snapshot, error) {
+	status, err := cleaner.GetSystemStatus(ctx)
+	if err != nil {
+		return metrics.Snapshot{}, err
+	}
+	snap := metrics.Snapshot{
+		Time:    time.Now(),
+		Version: Version,
+		Status:  status,
+		Health:  cleaner.EvaluateHealth(status, Config.Health),
+	}
+	entries, err := cleaner.LoadHistory(HistoryDir(), time.Time{})
+	if err != nil {
+		Logger.Warnf("Could not read run history for metrics: %v", err)
+	} else {
+		snap.History = cleaner.AnalyzeHistory(entries, time.Time{})
+	}
+	return snap, nil
+}
+
+// ServeMetrics serves Prometheus metrics on addr until ctx is canceled, refreshing
+// them every interval
+func ServeMetrics(ctx context.Context, addr string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid metrics interval %s", interval)
+	}
+	exporter := &metrics.Exporter{
+		Collect:  CollectMetrics,
+		Interval: interval,
+		OnError: func(err error) {
+			Logger.Errorf("Collecting metrics failed: %v", err)
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", exporter)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html><body><h1>wincleaner</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go exporter.Run(ctx)
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	})
+
+	fmt.Fprintf(Console(), "Serving metrics on http://%s/metrics (refresh every %s). Press Ctrl-C to stop.\n", addr, interval)
+	Logger.Infof("Serving metrics on %s, refreshing every %s", addr, interval)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	Logger.Info("Metrics server stopped")
+	return nil
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output and the `output` config key
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// RunReport is the single document emitted by a command in JSON or YAML output mode
+type RunReport struct {
+	Tool       string                     `json:"tool" yaml:"tool"`
+	Version    string                     `json:"version" yaml:"version"`
+	Command    string                     `json:"command" yaml:"command"`
+	RunID      string                     `json:"run_id" yaml:"run_id"`
+	DryRun     bool                       `json:"dry_run" yaml:"dry_run"`
+	StartTime  time.Time                  `json:"start_time" yaml:"start_time"`
+	EndTime    time.Time                  `json:"end_time" yaml:"end_time"`
+	Operations []*cleaner.OperationResult `json:"operations" yaml:"operations"`
+	Status     *cleaner.SystemStatus      `json:"status,omitempty" yaml:"status,omitempty"`
+	Quarantine []cleaner.QuarantineRun    `json:"quarantine,omitempty" yaml:"quarantine,omitempty"`
+	Audit      *cleaner.SettingsAudit     `json:"audit,omitempty" yaml:"audit,omitempty"`
+	Health     *cleaner.HealthReport      `json:"health,omitempty" yaml:"health,omitempty"`
+	Plan       []cleaner.PlanStep         `json:"plan,omitempty" yaml:"plan,omitempty"`
+	History    *cleaner.HistoryReport     `json:"history,omitempty" yaml:"history,omitempty"`
+	Service    *ServiceInfo               `json:"service,omitempty" yaml:"service,omitempty"`
+	Plans      []cleaner.MaintenancePlan  `json:"plans,omitempty" yaml:"plans,omitempty"`
+	PlanRun    *PlanRun                   `json:"plan_run,omitempty" yaml:"plan_run,omitempty"`
+	Errors     []string                   `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+var (
+	// OutputFormat is text, json or yaml; set via --output or the config file
+	OutputFormat string
+
+	// Version is the application version reported in run reports; set by main
+	Version string
+
+	// Report accumulates the results of the current command
+	Report RunReport
+)
+
+// ResolveOutputFormat settles the output format from the flag, the `output` config key
+// and the legacy `json_output` key, in that order of precedence
+func ResolveOutputFormat() error {
+	if OutputFormat == "" {
+		OutputFormat = Config.Output
+	}
+	if OutputFormat == "" && Config.JSONOutput {
+		OutputFormat = OutputJSON
+	}
+	switch OutputFormat {
+	case "":
+		OutputFormat = OutputText
+	case OutputText, OutputJSON, OutputYAML:
+	default:
+		return fmt.Errorf("invalid output format %q (expected text, json or yaml)", OutputFormat)
+	}
+	return nil
+}
+
+// Structured reports whether the command output is a JSON or YAML document
+func Structured() bool {
+	return OutputFormat == OutputJSON || OutputFormat == OutputYAML
+}
+
+// Console returns the writer for human-readable progress output. In structured
+// mode progress goes to stderr so stdout carries only the report document.
+func Console() io.Writer {
+	if Structured() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// BeginReport resets the report for the named command
+func BeginReport(command string) {
+	Report = newReport(command, RunID, DryRun)
+}
+
+// newReport returns an empty report for a run
+func newReport(command, runID string, dryRun bool) RunReport {
+	return RunReport{
+		Tool:       "wincleaner",
+		Version:    Version,
+		Command:    command,
+		RunID:      runID,
+		DryRun:     dryRun,
+		StartTime:  time.Now(),
+		Operations: []*cleaner.OperationResult{},
+	}
+}
+
+// RecordResult adds an operation result to the report
+func RecordResult(res *cleaner.OperationResult) {
+	cli.record(res)
+}
+
+// RecordStatus attaches system status information to the report
+func RecordStatus(status *cleaner.SystemStatus) {
+	Report.Status = status
+}
+
+// RecordQuarantine attaches a listing of the quarantine store to the report
+func RecordQuarantine(runs []cleaner.QuarantineRun) {
+	Report.Quarantine = runs
+}
+
+// RecordAudit attaches a settings audit to the report
+func RecordAudit(audit *cleaner.SettingsAudit) {
+	Report.Audit = audit
+}
+
+// RecordHealth attaches a health evaluation to the report
+func RecordHealth(health *cleaner.HealthReport) {
+	Report.Health = health
+}
+
+// RecordPlan attaches the operations recommended by 'auto' to the report
+func RecordPlan(plan []cleaner.PlanStep) {
+	Report.Plan = plan
+}
+
+// RecordHistory attaches a history summary to the report
+func RecordHistory(history *cleaner.HistoryReport) {
+	Report.History = history
+}
+
+// RecordService attaches the state of the Windows service to the report
+func RecordService(info *ServiceInfo) {
+	Report.Service = info
+}
+
+// RecordPlans attaches the configured maintenance plans to the report
+func RecordPlans(plans []cleaner.MaintenancePlan) {
+	Report.Plans = plans
+}
+
+// RecordError adds a command-level error to the report
+func RecordError(err error) {
+	Report.Errors = append(Report.Errors, err.Error())
+}
+
+// EmitReport writes the report to w in the configured structured format; it is a no-op in text mode
+func EmitReport(w io.Writer) error {
+	if !Structured() {
+		return nil
+	}
+	Report.EndTime = time.Now()
+	switch OutputFormat {
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(Report); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(Report)
+	}
+}
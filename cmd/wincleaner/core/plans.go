@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// Plan step states
+const (
+	StepRan     = "ran"
+	StepSkipped = "skipped" // its condition did not hold
+	StepNotRun  = "not_run" // the plan stopped or was canceled first
+)
+
+// PlanStepRun is what happened to one step of a plan run
+type PlanStepRun struct {
+	Operation string `json:"operation" yaml:"operation"`
+	State     string `json:"state" yaml:"state"`
+	// Status is the operation's result status, when it ran
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// PlanRun is the outcome of running a maintenance plan
+type PlanRun struct {
+	Plan  string        `json:"plan" yaml:"plan"`
+	Steps []PlanStepRun `json:"steps" yaml:"steps"`
+	// StoppedBy is the failed step that stopped the plan, if any
+	StoppedBy string `json:"stopped_by,omitempty" yaml:"stopped_by,omitempty"`
+}
+
+// FindPlan returns the configured plan with the given name
+func FindPlan(name string) (cleaner.MaintenancePlan, bool) {
+	for _, plan := range Config.Plans {
+		if plan.Name == name {
+			return plan, true
+		}
+	}
+	return cleaner.MaintenancePlan{}, false
+}
+
+// RunPlan runs a plan's steps in dependency order. A step whose condition does not hold
+// is skipped; a step that fails stops the plan unless it has continue_on_error. The
+// operation results are recorded in the run report as usual, and the plan run is
+// attached to it.
+func RunPlan(ctx context.Context, plan cleaner.MaintenancePlan) *PlanRun {
+	s := sessionFrom(ctx)
+	w := s.console()
+	run := &PlanRun{Plan: plan.Name}
+	defer func() { s.report.PlanRun = run }()
+
+	steps, err := plan.Order()
+	if err != nil {
+		fmt.Fprintf(w, "Cannot run plan %s: %v\n", plan.Name, err)
+		s.report.Errors = append(s.report.Errors, err.Error())
+		return run
+	}
+	fmt.Fprintf(w, "Running plan %s (%d steps)...\n", plan.Name, len(steps))
+	Logger.Infof("Running plan %s", plan.Name)
+
+	for _, step := range steps {
+		stepRun := PlanStepRun{Operation: step.Operation, State: StepNotRun}
+		switch {
+		case run.StoppedBy != "":
+			stepRun.Reason = fmt.Sprintf("the plan stopped after %s failed", run.StoppedBy)
+		case ctx.Err() != nil:
+			stepRun.Reason = ctx.Err().Error()
+		default:
+			op, ok := cleaner.LookupOperation(step.Operation)
+			if !ok {
+				// Validated when the config was loaded; only possible if the registry changed
+				stepRun.Reason = "unknown operation"
+				break
+			}
+			if ok, reason := step.When.Check(ctx); !ok {
+				stepRun.State, stepRun.Reason = StepSkipped, reason
+				fmt.Fprintf(w, "Skipping %s: %s.\n", op.Name, reason)
+				Logger.Infof("Plan %s: skipping %s: %s", plan.Name, op.ID, reason)
+				break
+			}
+
+			var res *cleaner.OperationResult
+			if step.Timeout > 0 {
+				res = RunRegisteredTimeout(ctx, op, step.Timeout)
+			} else {
+				res = RunRegistered(ctx, op)
+			}
+			stepRun.State, stepRun.Status = StepRan, res.Status
+			if res.Failed() && !step.ContinueOnError {
+				run.StoppedBy = step.Operation
+				fmt.Fprintf(w, "Stopping plan %s: %s did not succeed.\n", plan.Name, op.Name)
+				Logger.Warnf("Plan %s stopped: %s %s", plan.Name, op.ID, res.Status)
+			}
+		}
+		run.Steps = append(run.Steps, stepRun)
+	}
+
+	if run.StoppedBy == "" {
+		fmt.Fprintf(w, "Plan %s completed.\n", plan.Name)
+		Logger.Infof("Plan %s completed", plan.Name)
+	}
+	return run
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// ServiceName is the name wincleaner is registered under with the service manager
+const ServiceName = "wincleaner"
+
+const (
+	serviceDisplayName = "Windows Health Cleaner"
+	serviceDescription = "Runs scheduled maintenance and serves the REST API configured in the wincleaner config file."
+)
+
+// serviceStopTimeout is how long stopping the service may take. It leaves room for a
+// running operation to be canceled and its child processes killed.
+const serviceStopTimeout = 2 * cancelGrace
+
+// serviceStartTimeout is how long starting the service may take
+const serviceStartTimeout = 30 * time.Second
+
+// ServiceInfo describes the installed service
+type ServiceInfo struct {
+	Name      string `json:"name" yaml:"name"`
+	Installed bool   `json:"installed" yaml:"installed"`
+	State     string `json:"state,omitempty" yaml:"state,omitempty"`
+	StartType string `json:"start_type,omitempty" yaml:"start_type,omitempty"`
+	Command   string `json:"command,omitempty" yaml:"command,omitempty"`
+}
+
+// CheckService validates the configuration the service would run: what CheckDaemon
+// checks, and no schedule with only_when_idle. A service runs in session 0, where
+// GetLastInputInfo never sees the logged-on user's input, so the condition would
+// always be met.
+func CheckService() error {
+	if err := CheckDaemon(); err != nil {
+		return err
+	}
+	for i, cfg := range Config.Schedules {
+		if cfg.OnlyWhenIdle > 0 {
+			name := cfg.Name
+			if name == "" {
+				name = fmt.Sprintf("schedule-%d", i+1)
+			}
+			return fmt.Errorf("schedule %s: only_when_idle cannot be checked from a service, which does not see user input; run 'wincleaner daemon' in the user's session instead", name)
+		}
+	}
+	return nil
+}
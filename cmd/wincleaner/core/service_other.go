@@ -0,0 +1,42 @@
+//go:build !windows
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errNoServiceManager is returned by the service management functions off Windows
+var errNoServiceManager = fmt.Errorf("Windows services are not available on this platform; run 'wincleaner service run' in the foreground instead: %w", errors.ErrUnsupported)
+
+// InstallService is only supported on Windows
+func InstallService(configFile string) error {
+	return errNoServiceManager
+}
+
+// UninstallService is only supported on Windows
+func UninstallService() error {
+	return errNoServiceManager
+}
+
+// StartService is only supported on Windows
+func StartService() error {
+	return errNoServiceManager
+}
+
+// StopService is only supported on Windows
+func StopService() error {
+	return errNoServiceManager
+}
+
+// QueryService is only supported on Windows
+func QueryService() (*ServiceInfo, error) {
+	return nil, errNoServiceManager
+}
+
+// RunService runs the daemon in the foreground; there is no service manager to attach to
+func RunService(ctx context.Context) error {
+	return RunDaemon(ctx)
+}
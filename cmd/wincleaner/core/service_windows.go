@@ -0,0 +1,339 @@
+//go:build windows
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// servicePoll is how often a pending start or stop is checked
+const servicePoll = 250 * time.Millisecond
+
+// InstallService registers wincleaner as an automatically started service that runs
+// 'wincleaner service run' with the given config file
+func InstallService(configFile string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// The service starts in System32, so the config must be given by absolute path
+	configFile, err = filepath.Abs(configFile)
+	if err != nil {
+		return err
+	}
+
+	m, err := connectServiceManager()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(ServiceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", ServiceName)
+	}
+	s, err := m.CreateService(ServiceName, exe, mgr.Config{
+		DisplayName:      serviceDisplayName,
+		Description:      serviceDescription,
+		StartType:        mgr.StartAutomatic,
+		DelayedAutoStart: true,
+	}, "service", "run", "--config", configFile)
+	if err != nil {
+		return fmt.Errorf("creating service %s: %w", ServiceName, err)
+	}
+	defer s.Close()
+
+	// Restart after a failure, such as the API port being taken at boot
+	actions := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Minute},
+	}
+	if err := s.SetRecoveryActions(actions, uint32((24 * time.Hour).Seconds())); err != nil {
+		Logger.Warnf("Could not set recovery actions for service %s: %v", ServiceName, err)
+	}
+	Logger.Infof("Installed service %s running %s with config %s", ServiceName, exe, configFile)
+	return nil
+}
+
+// UninstallService stops the service if it is running and removes it
+func UninstallService() error {
+	m, err := connectServiceManager()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := openService(m)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if err := stopAndWait(s); err != nil {
+			return err
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("removing service %s: %w", ServiceName, err)
+	}
+	Logger.Infof("Uninstalled service %s", ServiceName)
+	return nil
+}
+
+// StartService starts the service and waits until it is running
+func StartService() error {
+	m, err := connectServiceManager()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := openService(m)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("starting service %s: %w", ServiceName, err)
+	}
+	status, err := waitForState(s, serviceStartTimeout)
+	if err != nil {
+		return err
+	}
+	if status.State != svc.Running {
+		return fmt.Errorf("service %s stopped while starting (exit code %d); see the log file", ServiceName, status.ServiceSpecificExitCode)
+	}
+	Logger.Infof("Started service %s", ServiceName)
+	return nil
+}
+
+// StopService stops the service and waits until it has stopped
+func StopService() error {
+	m, err := connectServiceManager()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := openService(m)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := stopAndWait(s); err != nil {
+		return err
+	}
+	Logger.Infof("Stopped service %s", ServiceName)
+	return nil
+}
+
+// QueryService returns the service's state and configuration. It only needs query
+// rights, so it works without administrator privileges.
+func QueryService() (*ServiceInfo, error) {
+	h, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the service manager: %w", err)
+	}
+	m := &mgr.Mgr{Handle: h}
+	defer m.Disconnect()
+
+	info := &ServiceInfo{Name: ServiceName}
+	sh, err := windows.OpenService(h, windows.StringToUTF16Ptr(ServiceName), windows.SERVICE_QUERY_STATUS|windows.SERVICE_QUERY_CONFIG)
+	if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening service %s: %w", ServiceName, err)
+	}
+	s := &mgr.Service{Name: ServiceName, Handle: sh}
+	defer s.Close()
+
+	info.Installed = true
+	status, err := s.Query()
+	if err != nil {
+		return nil, fmt.Errorf("querying service %s: %w", ServiceName, err)
+	}
+	info.State = serviceStateName(status.State)
+	config, err := s.Config()
+	if err != nil {
+		return nil, fmt.Errorf("reading service %s configuration: %w", ServiceName, err)
+	}
+	info.StartType = startTypeName(config)
+	info.Command = config.BinaryPathName
+	return info, nil
+}
+
+// RunService runs the daemon under the service control manager when the process was
+// started by it, and in the foreground otherwise
+func RunService(ctx context.Context) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		return RunDaemon(ctx)
+	}
+
+	// Services start in System32; resolve a relative log_file or data_dir against the
+	// executable's folder instead
+	if exe, err := os.Executable(); err == nil && os.Chdir(filepath.Dir(exe)) == nil {
+		SetupLogger()
+	}
+	Logger.Infof("Service %s starting", ServiceName)
+	return svc.Run(ServiceName, &serviceHandler{ctx: ctx})
+}
+
+// serviceHandler runs the daemon on behalf of the service control manager
+type serviceHandler struct {
+	ctx context.Context
+}
+
+// Execute implements svc.Handler. A daemon failure, such as the API server not being
+// able to listen, ends the service with exit code 1 so its recovery actions apply.
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		// The config may have changed since install
+		if err := CheckService(); err != nil {
+			done <- err
+			return
+		}
+		done <- RunDaemon(ctx)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	Logger.Infof("Service %s running", ServiceName)
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				Logger.Errorf("Service %s failed: %v", ServiceName, err)
+				return true, 1
+			}
+			Logger.Infof("Service %s stopped", ServiceName)
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				Logger.Infof("Service %s stopping", ServiceName)
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(serviceStopTimeout.Milliseconds())}
+				cancel()
+			}
+		}
+	}
+}
+
+// connectServiceManager connects with the full access that installing, starting and
+// stopping services requires
+func connectServiceManager() (*mgr.Mgr, error) {
+	m, err := mgr.Connect()
+	if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		return nil, fmt.Errorf("managing services requires administrator privileges (try 'wincleaner admin service ...'): %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the service manager: %w", err)
+	}
+	return m, nil
+}
+
+// openService opens the installed service
+func openService(m *mgr.Mgr) (*mgr.Service, error) {
+	s, err := m.OpenService(ServiceName)
+	if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+		return nil, fmt.Errorf("service %s is not installed", ServiceName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening service %s: %w", ServiceName, err)
+	}
+	return s, nil
+}
+
+// stopAndWait asks the service to stop and waits until it has
+func stopAndWait(s *mgr.Service) error {
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("stopping service %s: %w", ServiceName, err)
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+	status, err = waitForState(s, serviceStopTimeout)
+	if err != nil {
+		return err
+	}
+	if status.State != svc.Stopped {
+		return fmt.Errorf("service %s is %s after being asked to stop", ServiceName, serviceStateName(status.State))
+	}
+	return nil
+}
+
+// waitForState polls the service until it is no longer starting or stopping, or the
+// timeout expires
+func waitForState(s *mgr.Service, timeout time.Duration) (svc.Status, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := s.Query()
+		if err != nil {
+			return status, fmt.Errorf("querying service %s: %w", ServiceName, err)
+		}
+		if status.State != svc.StartPending && status.State != svc.StopPending {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return status, fmt.Errorf("service %s is still %s after %s", ServiceName, serviceStateName(status.State), timeout)
+		}
+		time.Sleep(servicePoll)
+	}
+}
+
+// serviceStateName names a service state
+func serviceStateName(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "stopped"
+	case svc.StartPending:
+		return "starting"
+	case svc.StopPending:
+		return "stopping"
+	case svc.Running:
+		return "running"
+	case svc.ContinuePending:
+		return "resuming"
+	case svc.PausePending:
+		return "pausing"
+	case svc.Paused:
+		return "paused"
+	default:
+		return fmt.Sprintf("state %d", state)
+	}
+}
+
+// startTypeName names a service's start type
+func startTypeName(config mgr.Config) string {
+	switch config.StartType {
+	case mgr.StartAutomatic:
+		if config.DelayedAutoStart {
+			return "automatic (delayed)"
+		}
+		return "automatic"
+	case mgr.StartManual:
+		return "manual"
+	case mgr.StartDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("type %d", config.StartType)
+	}
+}
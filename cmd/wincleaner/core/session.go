@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// session is the state of one run: the command-line invocation, or a job or scheduled
+// run inside a long-running process. Operations find their session in the context, so
+// runs never share or swap state. A session is used by one goroutine at a time.
+type session struct {
+	report *RunReport
+	// out receives the run's progress output; nil means the process console
+	out        io.Writer
+	quarantine *cleaner.Quarantine
+	journal    *cleaner.ChangeJournal
+}
+
+// sessionKey is the context key of the running session
+type sessionKey struct{}
+
+var (
+	// cli is the session of the command-line invocation; its report is Report
+	cli = &session{report: &Report}
+
+	// sessionMu serializes sessions, so that runs inside a long-running process never overlap
+	sessionMu sync.Mutex
+
+	// lastSessionRunID keeps back-to-back sessions from sharing a run ID; guarded by sessionMu
+	lastSessionRunID string
+)
+
+// sessionFrom returns the session running in ctx, or the command-line session
+func sessionFrom(ctx context.Context) *session {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return s
+	}
+	return cli
+}
+
+// RunSession runs fn as a self-contained run inside a long-running process (the API
+// server or the scheduler), as if it were a separate wincleaner invocation: it gets
+// its own run ID, report, quarantine store and change journal, its console output goes
+// to out, and its results are added to the history store. The operations fn runs must
+// be given the context it receives. Sessions run one at a time; the returned report
+// holds the session's results.
+func RunSession(ctx context.Context, command string, dryRun bool, out io.Writer, fn func(ctx context.Context)) RunReport {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+
+	// Run IDs have one-second resolution, and a shared ID would merge two sessions'
+	// quarantine and journal entries
+	runID := cleaner.NewRunID(time.Now())
+	for runID == lastSessionRunID || runID == RunID {
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+		runID = cleaner.NewRunID(time.Now())
+	}
+	lastSessionRunID = runID
+
+	report := newReport(command, runID, dryRun)
+	s := &session{report: &report, out: out}
+	fn(context.WithValue(ctx, sessionKey{}, s))
+	s.finish()
+	report.EndTime = time.Now()
+	return report
+}
+
+// console returns the writer for the session's progress output
+func (s *session) console() io.Writer {
+	if s.out != nil {
+		return s.out
+	}
+	return Console()
+}
+
+// options returns the cleaner options for the session's operations
+func (s *session) options() cleaner.Options {
+	opts := cleaner.Options{Verbose: Verbose, DryRun: s.report.DryRun, Out: s.console()}
+	if Quarantine || Config.Quarantine {
+		if s.quarantine == nil {
+			s.quarantine = cleaner.NewQuarantine(QuarantineDir(), s.report.RunID)
+		}
+		opts.Quarantine = s.quarantine
+	}
+	if s.journal == nil {
+		s.journal = cleaner.NewChangeJournal(JournalDir(), s.report.RunID)
+	}
+	opts.Journal = s.journal
+	return opts
+}
+
+// record adds an operation result to the session's report
+func (s *session) record(res *cleaner.OperationResult) {
+	s.report.Operations = append(s.report.Operations, res)
+}
+
+// finish tells the user how to undo the run's changes and adds the run to the history
+func (s *session) finish() {
+	s.quarantineNotice()
+	s.journalNotice()
+	s.saveHistory()
+}
+
+// quarantineNotice tells the user how to undo the run's deletions, if any were quarantined
+func (s *session) quarantineNotice() {
+	if s.quarantine == nil || s.quarantine.Stored() == 0 {
+		return
+	}
+	fmt.Fprintf(s.console(), "%d deleted files were quarantined. Restore them with: wincleaner restore %s\n", s.quarantine.Stored(), s.report.RunID)
+	Logger.Infof("Quarantined %d files under run %s", s.quarantine.Stored(), s.report.RunID)
+}
+
+// journalNotice tells the user how to undo the run's settings changes, if any were made
+func (s *session) journalNotice() {
+	if s.journal == nil || s.journal.Recorded() == 0 {
+		return
+	}
+	fmt.Fprintf(s.console(), "%d settings changes were journaled. Undo them with: wincleaner optimal --rollback %s\n", s.journal.Recorded(), s.report.RunID)
+	Logger.Infof("Journaled %d settings changes under run %s", s.journal.Recorded(), s.report.RunID)
+}
+
+// saveHistory appends the status and operation results of the run to the history store.
+// A failure to write history is logged but does not fail the run.
+func (s *session) saveHistory() {
+	r := s.report
+	entry := cleaner.NewHistoryEntry(r.RunID, r.Command, r.Status, r.Health, r.Operations)
+	if entry == nil {
+		return
+	}
+	if err := cleaner.AppendHistory(HistoryDir(), entry, Config.History); err != nil {
+		Logger.Warnf("Could not record run history: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// sessionTestOp reports the options it was run with
+var sessionTestOp = cleaner.Operation{
+	ID:   "session-test",
+	Name: "Session Test",
+	Run: func(ctx context.Context, opts cleaner.Options) (*cleaner.OperationResult, error) {
+		fmt.Fprintf(opts.Writer(), "dry run: %v\n", opts.DryRun)
+		return &cleaner.OperationResult{DryRun: opts.DryRun}, nil
+	},
+}
+
+func init() {
+	if err := cleaner.RegisterOperation(sessionTestOp); err != nil {
+		panic(err)
+	}
+}
+
+// useTestEnvironment points the data directory at a temporary one, silences the logger
+// and the console, and starts a fresh command-line report
+func useTestEnvironment(t *testing.T) {
+	t.Helper()
+	savedConfig, savedLogger, savedFormat := Config, Logger, OutputFormat
+	t.Cleanup(func() { Config, Logger, OutputFormat = savedConfig, savedLogger, savedFormat })
+
+	Config.DataDir = t.TempDir()
+	Logger = logrus.New()
+	Logger.SetOutput(io.Discard)
+	OutputFormat = OutputJSON
+	BeginReport("test")
+}
+
+// waitForJob polls until a job has finished
+func waitForJob(t *testing.T, m *JobManager, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := m.Get(id)
+		if !ok {
+			t.Fatalf("job %s is gone", id)
+		}
+		if job.finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+// Jobs and the command-line run operations at the same time; each must see only its own
+// dry-run setting, output and report. Run with -race.
+func TestSessionsKeepTheirOwnState(t *testing.T) {
+	useTestEnvironment(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewJobManager(ctx)
+
+	dryRuns := []bool{true, false}
+	var ids []string
+	for _, dryRun := range dryRuns {
+		job, err := m.Start([]string{sessionTestOp.ID}, dryRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	for range 3 {
+		RunRegistered(ctx, sessionTestOp)
+	}
+
+	runIDs := map[string]bool{RunID: true}
+	for i, id := range ids {
+		job := waitForJob(t, m, id)
+		if job.State != JobSucceeded {
+			t.Errorf("job %d: state %s, want %s", i, job.State, JobSucceeded)
+		}
+		if len(job.Results) != 1 || job.Results[0].DryRun != dryRuns[i] {
+			t.Errorf("job %d: results %+v, want one with dry run %v", i, job.Results, dryRuns[i])
+		}
+		if runIDs[job.RunID] {
+			t.Errorf("job %d: run ID %q is not unique", i, job.RunID)
+		}
+		runIDs[job.RunID] = true
+
+		log, _ := m.Log(id)
+		data, _, _, _ := log.ReadFrom(0)
+		if want := fmt.Sprintf("dry run: %v\n", dryRuns[i]); strings.Count(string(data), "dry run:") != 1 || !strings.Contains(string(data), want) {
+			t.Errorf("job %d: log %q, want one %q", i, data, want)
+		}
+	}
+
+	if len(Report.Operations) != 3 {
+		t.Fatalf("command-line report has %d operations, want 3", len(Report.Operations))
+	}
+	for _, res := range Report.Operations {
+		if res.DryRun {
+			t.Errorf("command-line operation ran as a dry run")
+		}
+	}
+}
+
+// A server started with --dry-run must not let a job change the system
+func TestJobsHonorGlobalDryRun(t *testing.T) {
+	useTestEnvironment(t)
+	saved := DryRun
+	t.Cleanup(func() { DryRun = saved })
+	DryRun = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewJobManager(ctx)
+
+	job, err := m.Start([]string{sessionTestOp.ID}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitForJob(t, m, job.ID)
+	if !job.DryRun || len(job.Results) != 1 || !job.Results[0].DryRun {
+		t.Errorf("job dry run %v, results %+v; want a dry run", job.DryRun, job.Results)
+	}
+}
+
+func TestRunSessionRecordsItsOwnReport(t *testing.T) {
+	useTestEnvironment(t)
+	var out strings.Builder
+	report := RunSession(context.Background(), "session", true, &out, func(ctx context.Context) {
+		RunRegistered(ctx, sessionTestOp)
+	})
+
+	if report.Command != "session" || !report.DryRun || report.RunID == RunID {
+		t.Errorf("report %+v: want command session, dry run and a run ID of its own", report)
+	}
+	if len(report.Operations) != 1 || report.Operations[0].Status != cleaner.StatusSuccess {
+		t.Errorf("operations %+v, want one success", report.Operations)
+	}
+	if !strings.Contains(out.String(), "dry run: true") {
+		t.Errorf("session output %q lacks the operation's output", out.String())
+	}
+	if len(Report.Operations) != 0 {
+		t.Errorf("the session's results leaked into the command-line report")
+	}
+}
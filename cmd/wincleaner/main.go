@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 	"github.com/user/windows_health/cmd/wincleaner/commands"
@@ -12,22 +14,41 @@ import (
 const version = "1.0.0"
 
 func main() {
+	// started is set once the arguments have been accepted and the command begins to run
+	started := false
 	rootCmd := &cobra.Command{
 		Use:   "wincleaner",
 		Short: "Windows Health Cleaner - A utility for system maintenance",
 		Long:  `Windows Health Cleaner is a comprehensive Windows system maintenance utility that helps keep your Windows system in optimal condition.`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			core.LoadConfig()
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			core.SetupLogger()
+			if err := core.ResolveOutputFormat(); err != nil {
+				return err
+			}
+			core.BeginReport(cmd.Name())
+			// From here on an error is the command failing, not a usage mistake
+			cmd.SilenceUsage = true
+			started = true
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			core.QuarantineNotice()
+			core.JournalNotice()
+			core.SaveHistory()
+			return core.EmitReport(os.Stdout)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(core.Config.DefaultOps) > 0 {
 				for _, op := range core.Config.DefaultOps {
-					for _, c := range cmd.Commands() {
-						if c.Name() == op {
-							c.Run(c, []string{})
-						}
+					c, _, err := cmd.Find([]string{op})
+					if err != nil || c == cmd {
+						fmt.Fprintf(core.Console(), "Unknown operation in default_ops: %s\n", op)
+						core.Logger.Warnf("Unknown operation in default_ops: %s", op)
+						core.RecordError(fmt.Errorf("unknown operation in default_ops: %s", op))
+						continue
 					}
+					c.SetContext(cmd.Context())
+					c.Run(c, []string{})
 				}
 			} else {
 				cmd.Help()
@@ -35,32 +56,55 @@ func main() {
 		},
 	}
 
+	core.Version = version
 	rootCmd.Version = version
+	// Errors are printed below, once, before exiting with the matching code
+	rootCmd.SilenceErrors = true
 	rootCmd.SetVersionTemplate("Windows Health Cleaner version {{.Version}}\n")
 	rootCmd.PersistentFlags().StringVar(&core.ConfigFile, "config", "", "Path to config YAML file")
 	rootCmd.PersistentFlags().BoolVarP(&core.Verbose, "verbose", "v", false, "Enable verbose logging to console")
+	rootCmd.PersistentFlags().StringVarP(&core.OutputFormat, "output", "o", "", "Output format: text, json or yaml")
+	rootCmd.PersistentFlags().BoolVar(&core.DryRun, "dry-run", false, "Report what each operation would do without making changes")
+	rootCmd.PersistentFlags().BoolVar(&core.Quarantine, "quarantine", false, "Move deleted files into the quarantine store so they can be restored")
+
+	// Load the config before building commands so custom targets become operations
+	core.ConfigFile = core.ConfigFileFromArgs(os.Args[1:])
+	if err := core.LoadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
 
+	rootCmd.AddCommand(commands.NewOperationCommands()...)
 	rootCmd.AddCommand(
-		commands.NewDiskCommand(),
-		commands.NewTempCommand(),
-		commands.NewEventsCommand(),
-		commands.NewSFCCommand(),
-		commands.NewDismCommand(),
-		commands.NewRecycleCommand(),
-		commands.NewOptimizeCommand(),
-		commands.NewChkdskCommand(),
-		commands.NewFlushDNSCommand(),
-		commands.NewMemcheckCommand(),
-		commands.NewPrefetchCommand(),
-		commands.NewResetNetCommand(),
 		commands.NewAllCommand(),
 		commands.NewStatusCommand(),
+		commands.NewAutoCommand(),
 		commands.NewOptimalCommand(),
 		commands.NewInteractiveCommand(),
+		commands.NewAdminCommand(),
+		commands.NewRestoreCommand(),
+		commands.NewQuarantineCommand(),
+		commands.NewHistoryCommand(),
+		commands.NewServeMetricsCommand(),
+		commands.NewServeCommand(),
+		commands.NewDaemonCommand(),
+		commands.NewServiceCommand(),
+		commands.NewPlanCommand(),
 	)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	// The first Ctrl-C cancels the running operation and kills its child processes;
+	// a second one terminates immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	context.AfterFunc(ctx, stop)
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if started {
+			os.Exit(core.ExitTotalFailure)
+		}
 		os.Exit(1)
 	}
+	os.Exit(core.ExitCode())
 }
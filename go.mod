@@ -5,6 +5,7 @@ go 1.24.1
 require (
 	github.com/sirupsen/logrus v1.9.0
 	github.com/spf13/cobra v1.8.0
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -13,6 +14,5 @@ require (
 	github.com/BurntSushi/toml v1.5.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
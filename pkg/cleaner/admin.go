@@ -15,6 +15,11 @@ func IsAdmin() bool {
 
 // RunAsAdmin restarts the current application with elevated privileges using UAC
 func RunAsAdmin() error {
+	return RunAsAdminWithArgs(os.Args[1:])
+}
+
+// RunAsAdminWithArgs restarts the current application elevated, passing it the given arguments
+func RunAsAdminWithArgs(args []string) error {
 	// Get the path to the current executable
 	exe, err := os.Executable()
 	if err != nil {
@@ -22,7 +27,7 @@ func RunAsAdmin() error {
 	}
 
 	// Prepare the runas command to request elevation
-	cmd := exec.Command("powershell.exe", "-Command", "Start-Process", "-FilePath", exe, "-ArgumentList", strings.Join(os.Args[1:], " "), "-Verb", "RunAs")
+	cmd := exec.Command("powershell.exe", "-Command", "Start-Process", "-FilePath", exe, "-ArgumentList", strings.Join(args, " "), "-Verb", "RunAs")
 
 	// Set up the command to create no window (HideWindow not available on this platform)
 	cmd.SysProcAttr = &syscall.SysProcAttr{}
@@ -1,63 +1,60 @@
 package cleaner
 
 import (
-	"os/exec"
-	"fmt"
+	"context"
 )
 
 // ClearEventLogs clears Windows event logs using the wevtutil command
-func ClearEventLogs(verbose bool) error {
-	cmd := exec.Command("wevtutil", "el")
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: wevtutil el")
-	}
-	output, err := cmd.Output()
+func ClearEventLogs(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	result, err := queryCommand(ctx, opts, res, "wevtutil", "el")
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	// Clear each event log
-	logs := splitLines(string(output))
+	logs := splitLines(string(result.Stdout))
 	for _, logName := range logs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
 		if logName == "" {
 			continue
 		}
-		clearCmd := exec.Command("wevtutil", "cl", logName)
-		if verbose {
-			fmt.Printf("[VERBOSE] Running command: wevtutil cl %s\n", logName)
-		}
-		clearCmd.Run() // Ignore errors, as some logs might be protected
+		runCommand(ctx, opts, res, "wevtutil", "cl", logName) // Ignore errors, as some logs might be protected
 	}
 
-	return nil
+	return res, nil
 }
 
 // RunSystemFileChecker runs the Windows System File Checker to repair system files
-func RunSystemFileChecker(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: sfc /scannow")
-	}
-	cmd := exec.Command("sfc", "/scannow")
-	return cmd.Run()
+func RunSystemFileChecker(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	_, err := runCommand(ctx, opts, res, "sfc", "/scannow")
+	return res, err
 }
 
 // RunDISM runs the Deployment Image Servicing and Management tool to repair Windows image
-func RunDISM(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: DISM /Online /Cleanup-Image /RestoreHealth")
-	}
-	cmd := exec.Command("DISM", "/Online", "/Cleanup-Image", "/RestoreHealth")
-	return cmd.Run()
+func RunDISM(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	_, err := runCommand(ctx, opts, res, "DISM", "/Online", "/Cleanup-Image", "/RestoreHealth")
+	return res, err
 }
 
 // EmptyRecycleBin empties the Windows Recycle Bin
-func EmptyRecycleBin(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: powershell -Command Clear-RecycleBin -Force")
-	}
+func EmptyRecycleBin(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
 	// Using PowerShell to clear recycle bin
-	cmd := exec.Command("powershell", "-Command", "Clear-RecycleBin", "-Force")
-	return cmd.Run()
+	_, err := runCommand(ctx, opts, res, "powershell", "-Command", "Clear-RecycleBin", "-Force")
+	return res, err
 }
 
 // Helper function to split command output into lines
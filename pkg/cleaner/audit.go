@@ -0,0 +1,114 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+)
+
+// AuditItem is the audit result for one setting
+type AuditItem struct {
+	// ID is the tweak ID, or "power_plan"
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	Desired   string `json:"desired" yaml:"desired"`
+	Current   string `json:"current" yaml:"current"`
+	Compliant bool   `json:"compliant" yaml:"compliant"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// SettingsAudit compares the current settings with a desired profile
+type SettingsAudit struct {
+	Compliant bool        `json:"compliant" yaml:"compliant"`
+	Items     []AuditItem `json:"items" yaml:"items"`
+}
+
+// NonCompliant returns how many audited settings differ from the profile or could not be read
+func (a *SettingsAudit) NonCompliant() int {
+	n := 0
+	for _, item := range a.Items {
+		if !item.Compliant {
+			n++
+		}
+	}
+	return n
+}
+
+// AuditOptimalProfile reads the current value of every setting the profile manages,
+// through reader and powercfg, and reports whether each matches. Nothing is changed.
+// Tweaks the profile skips are not audited.
+func AuditOptimalProfile(ctx context.Context, reader RegistryReader, profile OptimalProfile) (*SettingsAudit, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	audit := &SettingsAudit{Compliant: true}
+
+	if profile.PowerPlan != "" {
+		desired, _ := profile.powerScheme()
+		item := AuditItem{ID: "power_plan", Name: "Active Power Plan", Desired: powerPlanName(desired)}
+		current, err := activePowerScheme(ctx, Options{}, nil)
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Current = powerPlanName(current)
+			item.Compliant = current == desired
+		}
+		audit.add(item)
+	}
+
+	for _, tweak := range Tweaks {
+		if err := ctx.Err(); err != nil {
+			return audit, err
+		}
+		state := profile.Tweaks[tweak.ID]
+		if state == "" || state == TweakSkip {
+			continue
+		}
+		desired := tweak.Disabled
+		if state == TweakEnable {
+			desired = tweak.Enabled
+		}
+		item := AuditItem{ID: tweak.ID, Name: tweak.Name, Desired: fmt.Sprintf("%s (%s)", state, desired.Display())}
+		current, err := reader.GetValue(ctx, tweak.Path, tweak.Value)
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Current = current.Display()
+			if state := tweak.stateOf(current); state != "" {
+				item.Current = fmt.Sprintf("%s (%s)", state, current.Display())
+			}
+			item.Compliant = current.Equal(desired)
+		}
+		audit.add(item)
+	}
+	return audit, nil
+}
+
+// add appends an item, updating the overall verdict
+func (a *SettingsAudit) add(item AuditItem) {
+	a.Items = append(a.Items, item)
+	if !item.Compliant {
+		a.Compliant = false
+	}
+}
+
+// stateOf names the tweak state a registry value corresponds to, or "" if neither
+func (t Tweak) stateOf(value *RegistryValue) string {
+	switch {
+	case value.Equal(t.Enabled):
+		return TweakEnable
+	case value.Equal(t.Disabled):
+		return TweakDisable
+	default:
+		return ""
+	}
+}
+
+// powerPlanName returns the profile name of a built-in scheme GUID, or the GUID itself
+func powerPlanName(guid string) string {
+	for name, g := range powerPlans {
+		if g == guid {
+			return name
+		}
+	}
+	return guid
+}
@@ -0,0 +1,174 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// brokenRegistry is a RegistryReader whose reads all fail
+type brokenRegistry struct{}
+
+func (brokenRegistry) GetValue(ctx context.Context, path, name string) (*RegistryValue, error) {
+	return nil, errors.New("access denied")
+}
+
+func (brokenRegistry) KeyExists(ctx context.Context, path string) (bool, error) {
+	return false, errors.New("access denied")
+}
+
+// tweakValues returns a FakeRegistry holding the given values, keyed by tweak ID
+func tweakValues(t *testing.T, values map[string]*RegistryValue) *FakeRegistry {
+	t.Helper()
+	reg := NewFakeRegistry()
+	for id, value := range values {
+		tweak, ok := LookupTweak(id)
+		if !ok {
+			t.Fatalf("unknown tweak %q", id)
+		}
+		reg.Put(tweak.Path, tweak.Value, value)
+	}
+	return reg
+}
+
+func TestAuditOptimalProfile(t *testing.T) {
+	balanced := CommandResult{Stdout: []byte("Power Scheme GUID: 381B4222-F694-41F0-9685-FF5BB260DF2E  (Balanced)\r\n")}
+
+	// auditResult is the part of an AuditItem the tests check
+	type auditResult struct {
+		ID        string
+		Current   string
+		Compliant bool
+		Failed    bool
+	}
+
+	tests := []struct {
+		name    string
+		profile OptimalProfile
+		// values are the registry values present, keyed by tweak ID
+		values map[string]*RegistryValue
+		// reader replaces the FakeRegistry holding values
+		reader        RegistryReader
+		power         CommandResult
+		wantCompliant bool
+		want          []auditResult
+		wantErr       bool
+	}{
+		{
+			name:    "recommended profile applied",
+			profile: RecommendedProfile,
+			values: map[string]*RegistryValue{
+				"fast_boot": DWORDValue(0), "visual_effects": DWORDValue(2), "transparency": DWORDValue(0),
+				"storage_sense": DWORDValue(1), "startup_delay": DWORDValue(0),
+			},
+			power:         balanced,
+			wantCompliant: true,
+			want: []auditResult{
+				{ID: "power_plan", Current: "balanced", Compliant: true},
+				{ID: "fast_boot", Current: "enable (0)", Compliant: true},
+				{ID: "visual_effects", Current: "enable (2)", Compliant: true},
+				{ID: "transparency", Current: "enable (0)", Compliant: true},
+				{ID: "storage_sense", Current: "enable (1)", Compliant: true},
+				{ID: "startup_delay", Current: "enable (0)", Compliant: true},
+			},
+		},
+		{
+			name:    "settings that differ or are missing",
+			profile: OptimalProfile{Tweaks: map[string]string{"fast_boot": TweakEnable, "transparency": TweakEnable, "visual_effects": TweakEnable}},
+			values:  map[string]*RegistryValue{"fast_boot": DWORDValue(1), "visual_effects": DWORDValue(3)},
+			want: []auditResult{
+				{ID: "fast_boot", Current: "disable (1)"},
+				{ID: "visual_effects", Current: "3"},
+				{ID: "transparency", Current: "not set"},
+			},
+		},
+		{
+			name:          "a missing value is compliant when disabling deletes it",
+			profile:       OptimalProfile{Tweaks: map[string]string{"startup_delay": TweakDisable}},
+			wantCompliant: true,
+			want:          []auditResult{{ID: "startup_delay", Current: "disable (not set)", Compliant: true}},
+		},
+		{
+			name:          "skipped and unlisted tweaks are not audited",
+			profile:       OptimalProfile{Tweaks: map[string]string{"fast_boot": TweakSkip, "storage_sense": TweakDisable}},
+			values:        map[string]*RegistryValue{"storage_sense": DWORDValue(0), "fast_boot": DWORDValue(1)},
+			wantCompliant: true,
+			want:          []auditResult{{ID: "storage_sense", Current: "disable (0)", Compliant: true}},
+		},
+		{
+			name:    "a different power plan",
+			profile: OptimalProfile{PowerPlan: "high_performance"},
+			power:   balanced,
+			want:    []auditResult{{ID: "power_plan", Current: "balanced"}},
+		},
+		{
+			name:    "the power plan cannot be read",
+			profile: OptimalProfile{PowerPlan: "balanced"},
+			power:   CommandResult{ExitCode: 1},
+			want:    []auditResult{{ID: "power_plan", Failed: true}},
+		},
+		{
+			name:    "unexpected powercfg output",
+			profile: OptimalProfile{PowerPlan: "balanced"},
+			power:   CommandResult{Stdout: []byte("no scheme")},
+			want:    []auditResult{{ID: "power_plan", Failed: true}},
+		},
+		{
+			name:    "the registry cannot be read",
+			profile: OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable}},
+			reader:  brokenRegistry{},
+			want:    []auditResult{{ID: "transparency", Failed: true}},
+		},
+		{
+			name:    "unknown tweak",
+			profile: OptimalProfile{Tweaks: map[string]string{"turbo": TweakEnable}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown power plan",
+			profile: OptimalProfile{PowerPlan: "ludicrous"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeRunner(t)
+			fake.On(tt.power, "powercfg", "/getactivescheme")
+			reader := tt.reader
+			if reader == nil {
+				reader = tweakValues(t, tt.values)
+			}
+
+			audit, err := AuditOptimalProfile(context.Background(), reader, tt.profile)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("audit %+v, want an error", audit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []auditResult
+			for _, item := range audit.Items {
+				got = append(got, auditResult{ID: item.ID, Current: item.Current, Compliant: item.Compliant, Failed: item.Error != ""})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items %+v, want %+v", got, tt.want)
+			}
+			if audit.Compliant != tt.wantCompliant {
+				t.Errorf("compliant %v, want %v", audit.Compliant, tt.wantCompliant)
+			}
+			if nonCompliant := audit.NonCompliant(); (nonCompliant == 0) != tt.wantCompliant {
+				t.Errorf("%d settings non-compliant, want compliant %v", nonCompliant, tt.wantCompliant)
+			}
+			for _, call := range fake.Calls {
+				if call != "powercfg /getactivescheme" {
+					t.Errorf("the audit ran %q", call)
+				}
+			}
+		})
+	}
+}
@@ -1,35 +1,54 @@
 package cleaner
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"fmt"
+	"strings"
+	"time"
 )
 
 // RunDiskCleanup executes the Windows built-in Disk Cleanup utility (cleanmgr.exe)
-func RunDiskCleanup(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: cleanmgr /sageset:102")
-	}
+func RunDiskCleanup(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
 	// Using sageset and sagerun with a specific registry key (102)
 	// First, set up the configuration with sageset
-	setupCmd := exec.Command("cleanmgr", "/sageset:102")
-	err := setupCmd.Run()
-	if err != nil {
-		return err
+	if _, err := runCommand(ctx, opts, res, "cleanmgr", "/sageset:102"); err != nil {
+		return res, err
 	}
 
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: cleanmgr /sagerun:102")
-	}
 	// Then run the cleanup with the saved settings
-	cmd := exec.Command("cleanmgr", "/sagerun:102")
-	return cmd.Run()
+	_, err := runCommand(ctx, opts, res, "cleanmgr", "/sagerun:102")
+	return res, err
 }
 
 // CleanTempFiles removes files from Windows temporary directories
-func CleanTempFiles(verbose bool) error {
+func CleanTempFiles(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	for _, dir := range TempDirectories() {
+		if opts.Verbose {
+			fmt.Fprintf(opts.Writer(), "[VERBOSE] Cleaning temp directory: %s\n", dir)
+		}
+		if err := cleanDirectory(ctx, dir, TempPolicy, opts, res); err != nil {
+			return res, err
+		}
+	}
+
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would delete %d items totalling %s\n", res.ItemsRemoved, FormatBytes(float64(res.BytesFreed)))
+	}
+	return res, nil
+}
+
+// TempDirectories returns the system and user temporary directories, without duplicates
+func TempDirectories() []string {
 	// Get the Windows temp directory
 	tempDir := os.Getenv("TEMP")
 	if tempDir == "" {
@@ -40,51 +59,201 @@ func CleanTempFiles(verbose bool) error {
 	// Also clean user temp directory
 	userTempDir := filepath.Join(os.Getenv("USERPROFILE"), "AppData", "Local", "Temp")
 
-	if verbose {
-		fmt.Printf("[VERBOSE] Cleaning system temp directory: %s\n", tempDir)
+	dirs := []string{filepath.Clean(tempDir)}
+	if !strings.EqualFold(filepath.Clean(userTempDir), dirs[0]) {
+		dirs = append(dirs, filepath.Clean(userTempDir))
 	}
-	// Clean the system temp directory
-	if err := cleanDirectory(tempDir, verbose); err != nil {
-		return err
+	return dirs
+}
+
+// TempFolderSize returns the total size of the files in the temporary directories.
+// Unreadable files and directories are ignored.
+func TempFolderSize(ctx context.Context) (int64, error) {
+	var total int64
+	for _, dir := range TempDirectories() {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
 	}
+	return total, nil
+}
 
-	if verbose {
-		fmt.Printf("[VERBOSE] Cleaning user temp directory: %s\n", userTempDir)
+// cleanDirectory removes the files under dir selected by policy, tallying them in res.
+// Files that are locked, or modified while the scan is in progress, are skipped rather
+// than treated as errors. It stops early when ctx is done.
+func cleanDirectory(ctx context.Context, dir string, policy CleanPolicy, opts Options, res *OperationResult) error {
+	if err := policy.Validate(); err != nil {
+		return err
 	}
-	// Clean the user temp directory
-	return cleanDirectory(userTempDir, verbose)
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	_, err := cleanTree(ctx, dir, "", policy, opts, res, time.Now())
+	return err
 }
 
-// cleanDirectory removes files from the specified directory
-// It skips files that are in use and returns no error in that case
-func cleanDirectory(dir string, verbose bool) error {
+// cleanTree cleans one directory level and reports whether the directory is (or, in
+// dry-run mode, would be) left empty. rel is dir's slash-separated path below the root.
+func cleanTree(ctx context.Context, dir, rel string, policy CleanPolicy, opts Options, res *OperationResult, scanStart time.Time) (bool, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	empty := true
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
 		path := filepath.Join(dir, entry.Name())
+		entryRel := pathJoin(rel, entry.Name())
+
+		if policy.excluded(entryRel) {
+			empty = false
+			continue
+		}
+
+		if entry.IsDir() {
+			if !policy.Recursive {
+				empty = false
+				continue
+			}
+			subEmpty, err := cleanTree(ctx, path, entryRel, policy, opts, res, scanStart)
+			if err != nil {
+				if ctx.Err() != nil {
+					return false, err
+				}
+				res.skip(path, fmt.Sprintf("could not read directory: %v", err))
+				empty = false
+				continue
+			}
+			if !subEmpty || !policy.PruneEmptyDirs || !pruneDirectory(path, opts, res) {
+				empty = false
+			}
+			continue
+		}
+
+		// Leave symlinks, junctions and other special files alone
+		if !entry.Type().IsRegular() {
+			empty = false
+			continue
+		}
 
-		// Skip removal if it's a directory
 		info, err := entry.Info()
 		if err != nil {
 			// Just log and continue if we can't get file info
-			if verbose {
-				fmt.Printf("[VERBOSE] Could not get info for %s: %v\n", path, err)
+			if opts.Verbose {
+				fmt.Fprintf(opts.Writer(), "[VERBOSE] Could not get info for %s: %v\n", path, err)
 			}
+			res.skip(path, fmt.Sprintf("could not get file info: %v", err))
+			empty = false
 			continue
 		}
-
-		// Remove files only, not directories
-		if !info.IsDir() {
-			if verbose {
-				fmt.Printf("[VERBOSE] Removing file: %s\n", path)
+		if ok, reason := policy.selects(entryRel, info.Size(), info.ModTime(), scanStart); !ok {
+			if opts.Verbose {
+				fmt.Fprintf(opts.Writer(), "[VERBOSE] Keeping %s: %s\n", path, reason)
 			}
-			// Attempt to remove the file, ignore errors for files in use
-			os.Remove(path)
+			empty = false
+			continue
+		}
+		if !removeFile(path, info, opts, res, scanStart) {
+			empty = false
 		}
 	}
 
-	return nil
+	return empty, nil
+}
+
+// removeFile deletes a single file selected by the policy and reports whether it is gone
+// (or, in dry-run mode, would be)
+func removeFile(path string, info fs.FileInfo, opts Options, res *OperationResult, scanStart time.Time) bool {
+	// Skip files written since the scan started, or changed since we listed them
+	if info.ModTime().After(scanStart) {
+		res.skip(path, "modified during scan")
+		return false
+	}
+	if current, err := os.Lstat(path); err != nil || !current.ModTime().Equal(info.ModTime()) || current.Size() != info.Size() {
+		res.skip(path, "modified during scan")
+		return false
+	}
+
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would delete: %s (%s)\n", path, FormatBytes(float64(info.Size())))
+		res.removed(info.Size())
+		return true
+	}
+	if opts.Quarantine != nil {
+		if opts.Verbose {
+			fmt.Fprintf(opts.Writer(), "[VERBOSE] Quarantining file: %s\n", path)
+		}
+		if err := opts.Quarantine.Store(path, info); err != nil {
+			res.skip(path, removalSkipReason(err))
+			return false
+		}
+		res.removed(info.Size())
+		return true
+	}
+	if opts.Verbose {
+		fmt.Fprintf(opts.Writer(), "[VERBOSE] Removing file: %s\n", path)
+	}
+	// Attempt to remove the file, recording files in use as skipped
+	if err := os.Remove(path); err != nil {
+		res.skip(path, removalSkipReason(err))
+		return false
+	}
+	res.removed(info.Size())
+	return true
+}
+
+// pruneDirectory removes a directory emptied by the clean and reports whether it is gone
+func pruneDirectory(path string, opts Options, res *OperationResult) bool {
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would remove empty directory: %s\n", path)
+		res.removed(0)
+		return true
+	}
+	if opts.Verbose {
+		fmt.Fprintf(opts.Writer(), "[VERBOSE] Removing empty directory: %s\n", path)
+	}
+	// os.Remove refuses non-empty directories, so anything created meanwhile is kept
+	if err := os.Remove(path); err != nil {
+		res.skip(path, removalSkipReason(err))
+		return false
+	}
+	res.removed(0)
+	return true
+}
+
+// pathJoin joins slash-separated relative path elements
+func pathJoin(rel, name string) string {
+	if rel == "" {
+		return name
+	}
+	return rel + "/" + name
+}
+
+// removalSkipReason classifies why a file could not be removed
+func removalSkipReason(err error) string {
+	switch {
+	case isFileInUse(err):
+		return "file in use"
+	case errors.Is(err, fs.ErrPermission):
+		return "access denied"
+	case errors.Is(err, fs.ErrNotExist):
+		return "already removed"
+	default:
+		return err.Error()
+	}
 }
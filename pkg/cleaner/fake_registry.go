@@ -0,0 +1,152 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeRegistry is an in-memory Registry for tests and non-Windows development.
+// Like the real registry, key paths and value names are case-insensitive.
+type FakeRegistry struct {
+	mu     sync.Mutex
+	keys   map[string]bool
+	values map[string]*RegistryValue
+}
+
+// NewFakeRegistry returns an empty FakeRegistry
+func NewFakeRegistry() *FakeRegistry {
+	return &FakeRegistry{keys: make(map[string]bool), values: make(map[string]*RegistryValue)}
+}
+
+// Put stores a value, creating its key; a nil value removes it
+func (f *FakeRegistry) Put(path, name string, value *RegistryValue) *FakeRegistry {
+	if value == nil {
+		f.DeleteValue(context.Background(), path, name)
+	} else if err := f.SetValue(context.Background(), path, name, value); err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// fakeKey normalizes a key path, so that HKCU:\X and HKEY_CURRENT_USER\x are the same key
+func fakeKey(path string) (string, error) {
+	root, subkey, err := splitRegistryPath(path)
+	if err != nil {
+		return "", err
+	}
+	if subkey == "" {
+		return strings.ToLower(root), nil
+	}
+	return strings.ToLower(root + `\` + subkey), nil
+}
+
+// GetValue implements Registry
+func (f *FakeRegistry) GetValue(ctx context.Context, path, name string) (*RegistryValue, error) {
+	key, err := fakeKey(path)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	value, ok := f.values[key+`\`+strings.ToLower(name)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *value
+	return &copied, nil
+}
+
+// SetValue implements Registry
+func (f *FakeRegistry) SetValue(ctx context.Context, path, name string, value *RegistryValue) error {
+	if value.Kind != RegistryDWORD && value.Kind != RegistryString {
+		return fmt.Errorf("unsupported registry value kind %q", value.Kind)
+	}
+	if err := f.EnsureKey(ctx, path); err != nil {
+		return err
+	}
+	key, _ := fakeKey(path)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *value
+	f.values[key+`\`+strings.ToLower(name)] = &copied
+	return nil
+}
+
+// DeleteValue implements Registry
+func (f *FakeRegistry) DeleteValue(ctx context.Context, path, name string) error {
+	key, err := fakeKey(path)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delete(f.values, key+`\`+strings.ToLower(name))
+	return nil
+}
+
+// EnsureKey implements Registry
+func (f *FakeRegistry) EnsureKey(ctx context.Context, path string) error {
+	key, err := fakeKey(path)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for k := key; strings.Contains(k, `\`); k = k[:strings.LastIndex(k, `\`)] {
+		f.keys[k] = true
+	}
+	return nil
+}
+
+// KeyExists implements Registry; the roots always exist
+func (f *FakeRegistry) KeyExists(ctx context.Context, path string) (bool, error) {
+	key, err := fakeKey(path)
+	if err != nil {
+		return false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.keys[key] || !strings.Contains(key, `\`), ctx.Err()
+}
+
+// DeleteKey implements Registry
+func (f *FakeRegistry) DeleteKey(ctx context.Context, path string) error {
+	key, err := fakeKey(path)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(key, `\`) {
+		return fmt.Errorf("cannot delete registry root %s", path)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !f.keys[key] {
+		return nil
+	}
+	for k := range f.keys {
+		if strings.HasPrefix(k, key+`\`) {
+			return fmt.Errorf("%s: %w", path, ErrKeyNotEmpty)
+		}
+	}
+	for v := range f.values {
+		if strings.HasPrefix(v, key+`\`) {
+			return fmt.Errorf("%s: %w", path, ErrKeyNotEmpty)
+		}
+	}
+	delete(f.keys, key)
+	return nil
+}
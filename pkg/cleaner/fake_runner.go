@@ -0,0 +1,57 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeRunner is a CommandRunner that records every call and replays canned results.
+// Responses are keyed by the full command line as rendered by CommandLine.
+type FakeRunner struct {
+	mu sync.Mutex
+
+	// Responses maps a command line to the result it should produce
+	Responses map[string]CommandResult
+
+	// Default is returned for command lines without an entry in Responses
+	Default CommandResult
+
+	// Calls lists every command line run, in order
+	Calls []string
+}
+
+// NewFakeRunner returns a FakeRunner that succeeds with empty output unless told otherwise
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{Responses: make(map[string]CommandResult)}
+}
+
+// On registers the result to replay for the given command
+func (f *FakeRunner) On(result CommandResult, name string, args ...string) *FakeRunner {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Responses[CommandLine(name, args...)] = result
+	return f
+}
+
+// Run records the call and returns the canned result, failing when the exit code is
+// non-zero or ctx is already done
+func (f *FakeRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	line := CommandLine(name, args...)
+	f.Calls = append(f.Calls, line)
+	if err := ctx.Err(); err != nil {
+		return CommandResult{ExitCode: -1}, err
+	}
+
+	result, ok := f.Responses[line]
+	if !ok {
+		result = f.Default
+	}
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("exit status %d", result.ExitCode)
+	}
+	return result, nil
+}
@@ -0,0 +1,238 @@
+package cleaner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Finding severities, from best to worst
+const (
+	SeverityOK   = "ok"
+	SeverityWarn = "warn"
+	SeverityCrit = "crit"
+)
+
+// severityRank orders severities so the worst can be picked
+var severityRank = map[string]int{SeverityOK: 0, SeverityWarn: 1, SeverityCrit: 2}
+
+// Score penalties per finding
+const (
+	warnPenalty = 10
+	critPenalty = 25
+)
+
+// HealthThresholds configures when health checks warn or go critical; the `health`
+// config section overrides DefaultHealthThresholds. A zero threshold disables that level.
+type HealthThresholds struct {
+	// DiskFreeWarnPercent and DiskFreeCritPercent apply to each fixed volume's free space
+	DiskFreeWarnPercent float64 `yaml:"disk_free_warn_percent" json:"disk_free_warn_percent"`
+	DiskFreeCritPercent float64 `yaml:"disk_free_crit_percent" json:"disk_free_crit_percent"`
+	// UptimeWarn and UptimeCrit flag systems that have not restarted in a long time
+	UptimeWarn time.Duration `yaml:"uptime_warn" json:"uptime_warn"`
+	UptimeCrit time.Duration `yaml:"uptime_crit" json:"uptime_crit"`
+	// MemoryWarnPercent and MemoryCritPercent apply to physical memory and commit charge
+	MemoryWarnPercent float64 `yaml:"memory_warn_percent" json:"memory_warn_percent"`
+	MemoryCritPercent float64 `yaml:"memory_crit_percent" json:"memory_crit_percent"`
+	// TempWarnSize and TempCritSize apply to the total size of the temporary directories
+	TempWarnSize ByteSize `yaml:"temp_warn_size" json:"temp_warn_size"`
+	TempCritSize ByteSize `yaml:"temp_crit_size" json:"temp_crit_size"`
+	// PendingReboot is the severity of a pending reboot: warn, crit, or ok to ignore it
+	PendingReboot string `yaml:"pending_reboot" json:"pending_reboot"`
+	// StoppedServices is the severity of stopped automatic (non-delayed) services
+	StoppedServices string `yaml:"stopped_services" json:"stopped_services"`
+	// DNSFailure is the severity of a failed name resolution check
+	DNSFailure string `yaml:"dns_failure" json:"dns_failure"`
+}
+
+// DefaultHealthThresholds are used for settings the config does not override
+var DefaultHealthThresholds = HealthThresholds{
+	DiskFreeWarnPercent: 20,
+	DiskFreeCritPercent: 10,
+	UptimeWarn:          30 * 24 * time.Hour,
+	UptimeCrit:          90 * 24 * time.Hour,
+	MemoryWarnPercent:   85,
+	MemoryCritPercent:   95,
+	TempWarnSize:        1 << 30,
+	TempCritSize:        5 << 30,
+	PendingReboot:       SeverityWarn,
+	StoppedServices:     SeverityWarn,
+	DNSFailure:          SeverityWarn,
+}
+
+// Finding is the outcome of one health check
+type Finding struct {
+	// Check names the rule, e.g. "disk_space" or "uptime"
+	Check    string `json:"check" yaml:"check"`
+	Subject  string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+	// Operation is the wincleaner operation that addresses the finding, if there is one
+	Operation string `json:"operation,omitempty" yaml:"operation,omitempty"`
+	// Advice is a manual step, or a further operation, that also helps
+	Advice string `json:"advice,omitempty" yaml:"advice,omitempty"`
+}
+
+// HealthReport is the verdict on a SystemStatus
+type HealthReport struct {
+	// Score runs from 100 (healthy) down to 0
+	Score int `json:"score" yaml:"score"`
+	// Verdict is the worst severity among the findings
+	Verdict  string    `json:"verdict" yaml:"verdict"`
+	Findings []Finding `json:"findings" yaml:"findings"`
+}
+
+// Problems returns the findings that are not OK
+func (h *HealthReport) Problems() []Finding {
+	var problems []Finding
+	for _, f := range h.Findings {
+		if f.Severity != SeverityOK {
+			problems = append(problems, f)
+		}
+	}
+	return problems
+}
+
+// EvaluateHealth applies the thresholds to a status. Sections missing from the status
+// (because their collector failed) are not evaluated.
+func EvaluateHealth(status *SystemStatus, t HealthThresholds) *HealthReport {
+	h := &HealthReport{Score: 100, Verdict: SeverityOK}
+
+	for _, drive := range status.Drives() {
+		info := status.DiskSpace[drive]
+		if info.DriveType != "" && info.DriveType != "fixed" {
+			continue
+		}
+		free := 100 - info.UsedPercent
+		h.add(Finding{
+			Check:     "disk_space",
+			Subject:   drive,
+			Severity:  below(free, t.DiskFreeWarnPercent, t.DiskFreeCritPercent),
+			Message:   fmt.Sprintf("%.1f%% free (%s of %s)", free, FormatBytes(float64(info.FreeBytes)), FormatBytes(float64(info.TotalBytes))),
+			Operation: "disk",
+			Advice:    "Also try wincleaner temp and wincleaner recycle",
+		})
+	}
+
+	if !status.LastBootTime.IsZero() {
+		days := status.Uptime.Hours() / 24
+		h.add(Finding{
+			Check:    "uptime",
+			Severity: above(float64(status.Uptime), float64(t.UptimeWarn), float64(t.UptimeCrit)),
+			Message:  fmt.Sprintf("up for %.1f days", days),
+			Advice:   "Restart the computer",
+		})
+	}
+
+	if r := status.PendingReboot; r != nil {
+		f := Finding{Check: "pending_reboot", Severity: SeverityOK, Message: "no reboot pending"}
+		if r.Required() && t.PendingReboot != "" {
+			f.Severity, f.Message, f.Advice = t.PendingReboot, "a restart is pending to finish installing changes", "Restart the computer"
+		}
+		h.add(f)
+	}
+
+	if m := status.Memory; m != nil {
+		h.add(Finding{
+			Check:    "memory",
+			Severity: above(m.UsedPercent, t.MemoryWarnPercent, t.MemoryCritPercent),
+			Message:  fmt.Sprintf("%.1f%% of physical memory in use", m.UsedPercent),
+			Advice:   "Close unused applications",
+		})
+		if m.CommitLimitBytes > 0 {
+			h.add(Finding{
+				Check:    "commit_charge",
+				Severity: above(m.CommitPercent, t.MemoryWarnPercent, t.MemoryCritPercent),
+				Message:  fmt.Sprintf("%.1f%% of the commit limit in use", m.CommitPercent),
+				Advice:   "Close unused applications or enlarge the page file",
+			})
+		}
+	}
+
+	if status.TempFolderBytes != nil {
+		size := *status.TempFolderBytes
+		h.add(Finding{
+			Check:     "temp_size",
+			Severity:  above(float64(size), float64(t.TempWarnSize), float64(t.TempCritSize)),
+			Message:   fmt.Sprintf("temporary files use %s", FormatBytes(float64(size))),
+			Operation: "temp",
+		})
+	}
+
+	if d := status.DNS; d != nil {
+		f := Finding{Check: "dns", Severity: SeverityOK, Message: "resolved " + d.Host}
+		if !d.Resolved && t.DNSFailure != "" {
+			f.Severity, f.Message = t.DNSFailure, fmt.Sprintf("could not resolve %s: %s", d.Host, d.Error)
+			f.Operation, f.Advice = "flushdns", "If that does not help, check the network connection or run wincleaner resetnet"
+		}
+		h.add(f)
+	}
+
+	var stopped []string
+	for _, svc := range status.StoppedServices {
+		if !svc.DelayedStart {
+			stopped = append(stopped, svc.Name)
+		}
+	}
+	if len(stopped) > 0 && t.StoppedServices != "" {
+		h.add(Finding{
+			Check:    "services",
+			Severity: t.StoppedServices,
+			Message:  fmt.Sprintf("%d automatic services are stopped: %s", len(stopped), strings.Join(stopped, ", ")),
+			Advice:   "Check the services in services.msc",
+		})
+	}
+
+	return h
+}
+
+// add records a finding, dropping the recommendation from OK findings and updating the score
+func (h *HealthReport) add(f Finding) {
+	switch f.Severity {
+	case SeverityOK:
+		f.Operation, f.Advice = "", ""
+	case SeverityWarn:
+		h.Score -= warnPenalty
+	case SeverityCrit:
+		h.Score -= critPenalty
+	}
+	h.Score = max(h.Score, 0)
+	if severityRank[f.Severity] > severityRank[h.Verdict] {
+		h.Verdict = f.Severity
+	}
+	h.Findings = append(h.Findings, f)
+}
+
+// above grades a value that is bad when high
+func above(value, warn, crit float64) string {
+	switch {
+	case crit > 0 && value >= crit:
+		return SeverityCrit
+	case warn > 0 && value >= warn:
+		return SeverityWarn
+	default:
+		return SeverityOK
+	}
+}
+
+// below grades a value that is bad when low
+func below(value, warn, crit float64) string {
+	switch {
+	case crit > 0 && value < crit:
+		return SeverityCrit
+	case warn > 0 && value < warn:
+		return SeverityWarn
+	default:
+		return SeverityOK
+	}
+}
+
+// Validate checks the configured severities
+func (t HealthThresholds) Validate() error {
+	for name, severity := range map[string]string{"pending_reboot": t.PendingReboot, "stopped_services": t.StoppedServices, "dns_failure": t.DNSFailure} {
+		if _, ok := severityRank[severity]; !ok && severity != "" {
+			return fmt.Errorf("%s: severity must be ok, warn or crit, not %q", name, severity)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package cleaner
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// historyFile is the JSON lines file, inside the history directory, holding one entry per run
+const historyFile = "history.jsonl"
+
+// historyLock is the file, next to the history file, whose lock serializes the processes
+// that write the history store. The history file cannot carry the lock itself, since
+// pruning replaces it.
+const historyLock = "history.lock"
+
+// minTrendSpan is the shortest time span a growth rate is computed over
+const minTrendSpan = time.Hour
+
+// HistoryDisk is the capacity of one drive at the time of a history entry
+type HistoryDisk struct {
+	TotalBytes uint64 `json:"total_bytes" yaml:"total_bytes"`
+	FreeBytes  uint64 `json:"free_bytes" yaml:"free_bytes"`
+}
+
+// HistoryOperation is the outcome of one operation recorded in a history entry
+type HistoryOperation struct {
+	Operation  string        `json:"operation" yaml:"operation"`
+	Status     string        `json:"status" yaml:"status"`
+	BytesFreed int64         `json:"bytes_freed" yaml:"bytes_freed"`
+	Duration   time.Duration `json:"duration_ns" yaml:"duration"`
+	Error      string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// HistoryEntry is what one wincleaner run contributes to the history store: the status
+// snapshot it took, if any, and the operations it ran
+type HistoryEntry struct {
+	Time            time.Time              `json:"time" yaml:"time"`
+	RunID           string                 `json:"run_id" yaml:"run_id"`
+	Command         string                 `json:"command" yaml:"command"`
+	Disks           map[string]HistoryDisk `json:"disks,omitempty" yaml:"disks,omitempty"`
+	TempFolderBytes *int64                 `json:"temp_folder_bytes,omitempty" yaml:"temp_folder_bytes,omitempty"`
+	HealthScore     *int                   `json:"health_score,omitempty" yaml:"health_score,omitempty"`
+	Operations      []HistoryOperation     `json:"operations,omitempty" yaml:"operations,omitempty"`
+}
+
+// NewHistoryEntry summarizes a run for the history store. Dry-run results are left out,
+// since they changed nothing. The entry is nil when there is nothing to record.
+func NewHistoryEntry(runID, command string, status *SystemStatus, health *HealthReport, results []*OperationResult) *HistoryEntry {
+	entry := &HistoryEntry{Time: time.Now(), RunID: runID, Command: command}
+	if status != nil {
+		for drive, info := range status.DiskSpace {
+			if entry.Disks == nil {
+				entry.Disks = make(map[string]HistoryDisk)
+			}
+			entry.Disks[drive] = HistoryDisk{TotalBytes: info.TotalBytes, FreeBytes: info.FreeBytes}
+		}
+		entry.TempFolderBytes = status.TempFolderBytes
+	}
+	if health != nil {
+		score := health.Score
+		entry.HealthScore = &score
+	}
+	for _, res := range results {
+		if res.DryRun {
+			continue
+		}
+		entry.Operations = append(entry.Operations, HistoryOperation{
+			Operation:  res.Operation,
+			Status:     res.Status,
+			BytesFreed: res.BytesFreed,
+			Duration:   res.Duration,
+			Error:      res.Error,
+		})
+	}
+	if entry.Disks == nil && entry.TempFolderBytes == nil && entry.HealthScore == nil && len(entry.Operations) == 0 {
+		return nil
+	}
+	return entry
+}
+
+// HistoryRetention limits the size of the history store. Entries older than MaxAge,
+// and all but the newest MaxEntries, are pruned whenever an entry is appended; a zero
+// value disables that limit.
+type HistoryRetention struct {
+	MaxAge     time.Duration `yaml:"max_age" json:"max_age"`
+	MaxEntries int           `yaml:"max_entries" json:"max_entries"`
+}
+
+// DefaultHistoryRetention keeps a year of history, and at most 10000 entries
+var DefaultHistoryRetention = HistoryRetention{MaxAge: 365 * 24 * time.Hour, MaxEntries: 10000}
+
+// Validate checks that the limits are not negative
+func (r HistoryRetention) Validate() error {
+	if r.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative, got %s", r.MaxAge)
+	}
+	if r.MaxEntries < 0 {
+		return fmt.Errorf("max_entries must not be negative, got %d", r.MaxEntries)
+	}
+	return nil
+}
+
+// AppendHistory adds an entry to the history store in dir, then prunes the entries
+// the retention limits no longer allow. The daemon, API jobs and command-line runs may
+// append at the same time, so both steps hold the store's lock; otherwise an entry
+// appended while another process prunes would be lost.
+func AppendHistory(dir string, entry *HistoryEntry, retention HistoryRetention) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	unlock, err := lockHistory(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(filepath.Join(dir, historyFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return pruneHistory(dir, retention, entry.Time)
+}
+
+// lockHistory waits for the exclusive lock on the history store in dir and returns
+// the function that releases it
+func lockHistory(dir string) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(dir, historyLock), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock the history store: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// pruneHistory rewrites the history file without the entries that are older than the
+// retention's MaxAge at now or beyond its MaxEntries, and without unreadable lines.
+// The file is only rewritten when something is dropped. The caller holds the lock.
+func pruneHistory(dir string, retention HistoryRetention, now time.Time) error {
+	if retention.MaxAge <= 0 && retention.MaxEntries <= 0 {
+		return nil
+	}
+	path := filepath.Join(dir, historyFile)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	var cutoff time.Time
+	if retention.MaxAge > 0 {
+		cutoff = now.Add(-retention.MaxAge)
+	}
+
+	// Entries are appended in time order, so the newest are the last lines
+	var kept [][]byte
+	dropped := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Time.Before(cutoff) {
+			dropped++
+			continue
+		}
+		kept = append(kept, append([]byte(nil), scanner.Bytes()...))
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if retention.MaxEntries > 0 && len(kept) > retention.MaxEntries {
+		dropped += len(kept) - retention.MaxEntries
+		kept = kept[len(kept)-retention.MaxEntries:]
+	}
+	if dropped == 0 {
+		return nil
+	}
+
+	tmp, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, line := range kept {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(path + ".tmp")
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// LoadHistory reads the entries recorded at or after since, oldest first. Lines that
+// cannot be parsed (e.g. one cut short by a crash) are skipped.
+func LoadHistory(dir string, since time.Time) ([]HistoryEntry, error) {
+	f, err := os.Open(filepath.Join(dir, historyFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, scanner.Err()
+}
+
+// DiskTrend is the change in one drive's free space over the history window
+type DiskTrend struct {
+	Drive      string    `json:"drive" yaml:"drive"`
+	Samples    int       `json:"samples" yaml:"samples"`
+	First      time.Time `json:"first" yaml:"first"`
+	Last       time.Time `json:"last" yaml:"last"`
+	TotalBytes uint64    `json:"total_bytes" yaml:"total_bytes"`
+	FirstFree  uint64    `json:"first_free_bytes" yaml:"first_free_bytes"`
+	LastFree   uint64    `json:"last_free_bytes" yaml:"last_free_bytes"`
+	// UsedGrowthPerDay is the fitted growth of used space in bytes per day; negative when
+	// space is being freed. It is nil when the samples span less than an hour.
+	UsedGrowthPerDay *float64 `json:"used_growth_bytes_per_day,omitempty" yaml:"used_growth_bytes_per_day,omitempty"`
+	// DaysUntilFull projects when the drive fills at that rate; nil when it is not filling
+	DaysUntilFull *float64 `json:"days_until_full,omitempty" yaml:"days_until_full,omitempty"`
+}
+
+// SizeTrend is the change in a size, such as the temp folders', over the history window
+type SizeTrend struct {
+	Samples      int       `json:"samples" yaml:"samples"`
+	First        time.Time `json:"first" yaml:"first"`
+	Last         time.Time `json:"last" yaml:"last"`
+	FirstBytes   int64     `json:"first_bytes" yaml:"first_bytes"`
+	LastBytes    int64     `json:"last_bytes" yaml:"last_bytes"`
+	GrowthPerDay *float64  `json:"growth_bytes_per_day,omitempty" yaml:"growth_bytes_per_day,omitempty"`
+}
+
+// OperationHistory totals the recorded runs of one operation
+type OperationHistory struct {
+	Operation  string    `json:"operation" yaml:"operation"`
+	Runs       int       `json:"runs" yaml:"runs"`
+	Failures   int       `json:"failures" yaml:"failures"`
+	BytesFreed int64     `json:"bytes_freed" yaml:"bytes_freed"`
+	LastRun    time.Time `json:"last_run" yaml:"last_run"`
+	LastStatus string    `json:"last_status" yaml:"last_status"`
+}
+
+// HistoryDay is the last reading of each day, with that day's operation outcomes
+type HistoryDay struct {
+	Date             string            `json:"date" yaml:"date"`
+	FreeBytes        map[string]uint64 `json:"free_bytes,omitempty" yaml:"free_bytes,omitempty"`
+	TempFolderBytes  *int64            `json:"temp_folder_bytes,omitempty" yaml:"temp_folder_bytes,omitempty"`
+	HealthScore      *int              `json:"health_score,omitempty" yaml:"health_score,omitempty"`
+	OperationsRun    int               `json:"operations_run" yaml:"operations_run"`
+	OperationsFailed int               `json:"operations_failed" yaml:"operations_failed"`
+}
+
+// HistoryReport summarizes the history store over a window
+type HistoryReport struct {
+	Since      time.Time          `json:"since" yaml:"since"`
+	Entries    int                `json:"entries" yaml:"entries"`
+	Disks      []DiskTrend        `json:"disks,omitempty" yaml:"disks,omitempty"`
+	Temp       *SizeTrend         `json:"temp,omitempty" yaml:"temp,omitempty"`
+	Operations []OperationHistory `json:"operations,omitempty" yaml:"operations,omitempty"`
+	Days       []HistoryDay       `json:"days,omitempty" yaml:"days,omitempty"`
+}
+
+// AnalyzeHistory computes trends from entries sorted oldest first
+func AnalyzeHistory(entries []HistoryEntry, since time.Time) *HistoryReport {
+	report := &HistoryReport{Since: since, Entries: len(entries)}
+
+	type point struct {
+		t time.Time
+		v float64
+	}
+	diskPoints := make(map[string][]point)
+	disks := make(map[string]*DiskTrend)
+	var tempPoints []point
+	ops := make(map[string]*OperationHistory)
+	days := make(map[string]*HistoryDay)
+	var dayOrder []string
+
+	for _, entry := range entries {
+		date := entry.Time.Local().Format("2006-01-02")
+		day, ok := days[date]
+		if !ok {
+			day = &HistoryDay{Date: date}
+			days[date] = day
+			dayOrder = append(dayOrder, date)
+		}
+
+		for drive, disk := range entry.Disks {
+			trend, ok := disks[drive]
+			if !ok {
+				trend = &DiskTrend{Drive: drive, First: entry.Time, FirstFree: disk.FreeBytes}
+				disks[drive] = trend
+			}
+			trend.Samples++
+			trend.Last, trend.LastFree, trend.TotalBytes = entry.Time, disk.FreeBytes, disk.TotalBytes
+			diskPoints[drive] = append(diskPoints[drive], point{entry.Time, float64(disk.TotalBytes) - float64(disk.FreeBytes)})
+			if day.FreeBytes == nil {
+				day.FreeBytes = make(map[string]uint64)
+			}
+			day.FreeBytes[drive] = disk.FreeBytes
+		}
+
+		if entry.TempFolderBytes != nil {
+			size := *entry.TempFolderBytes
+			if report.Temp == nil {
+				report.Temp = &SizeTrend{First: entry.Time, FirstBytes: size}
+			}
+			report.Temp.Samples++
+			report.Temp.Last, report.Temp.LastBytes = entry.Time, size
+			tempPoints = append(tempPoints, point{entry.Time, float64(size)})
+			day.TempFolderBytes = entry.TempFolderBytes
+		}
+		if entry.HealthScore != nil {
+			day.HealthScore = entry.HealthScore
+		}
+
+		for _, op := range entry.Operations {
+			stats, ok := ops[op.Operation]
+			if !ok {
+				stats = &OperationHistory{Operation: op.Operation}
+				ops[op.Operation] = stats
+			}
+			stats.Runs++
+			stats.BytesFreed += op.BytesFreed
+			stats.LastRun, stats.LastStatus = entry.Time, op.Status
+			day.OperationsRun++
+			if op.Status != StatusSuccess {
+				stats.Failures++
+				day.OperationsFailed++
+			}
+		}
+	}
+
+	// slope fits a least-squares line and returns its slope per day
+	slope := func(points []point) *float64 {
+		if len(points) < 2 || points[len(points)-1].t.Sub(points[0].t) < minTrendSpan {
+			return nil
+		}
+		origin := points[0].t
+		var sumX, sumY, sumXX, sumXY float64
+		for _, p := range points {
+			x := p.t.Sub(origin).Hours() / 24
+			sumX += x
+			sumY += p.v
+			sumXX += x * x
+			sumXY += x * p.v
+		}
+		n := float64(len(points))
+		denominator := n*sumXX - sumX*sumX
+		if denominator == 0 {
+			return nil
+		}
+		perDay := (n*sumXY - sumX*sumY) / denominator
+		return &perDay
+	}
+
+	for drive, trend := range disks {
+		trend.UsedGrowthPerDay = slope(diskPoints[drive])
+		if g := trend.UsedGrowthPerDay; g != nil && *g > 0 {
+			days := float64(trend.LastFree) / *g
+			trend.DaysUntilFull = &days
+		}
+		report.Disks = append(report.Disks, *trend)
+	}
+	sort.Slice(report.Disks, func(i, j int) bool { return report.Disks[i].Drive < report.Disks[j].Drive })
+
+	if report.Temp != nil {
+		report.Temp.GrowthPerDay = slope(tempPoints)
+	}
+
+	for _, stats := range ops {
+		report.Operations = append(report.Operations, *stats)
+	}
+	sort.Slice(report.Operations, func(i, j int) bool { return report.Operations[i].Operation < report.Operations[j].Operation })
+
+	for _, date := range dayOrder {
+		report.Days = append(report.Days, *days[date])
+	}
+	return report
+}
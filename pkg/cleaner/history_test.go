@@ -0,0 +1,317 @@
+package cleaner
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// historyStart is the time of the first entry in the history tests
+var historyStart = time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+
+// diskSample is the free space on C: some time after historyStart
+type diskSample struct {
+	after time.Duration
+	free  uint64
+}
+
+const (
+	gb        = 1 << 30
+	day       = 24 * time.Hour
+	diskTotal = 100 * gb
+)
+
+// floatPtr returns a pointer to v, for expected trend values
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// approxEqual compares optional values to within a small relative error
+func approxEqual(got, want *float64) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return math.Abs(*got-*want) <= 1e-9*math.Max(1, math.Abs(*want))
+}
+
+// format renders an optional value for failure messages
+func format(v *float64) string {
+	if v == nil {
+		return "nil"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func TestAnalyzeHistoryDiskTrend(t *testing.T) {
+	tests := []struct {
+		name          string
+		samples       []diskSample
+		wantGrowth    *float64
+		wantDaysUntil *float64
+	}{
+		{
+			name:          "filling steadily",
+			samples:       []diskSample{{0, 54 * gb}, {day, 53 * gb}, {2 * day, 52 * gb}, {3 * day, 51 * gb}, {4 * day, 50 * gb}},
+			wantGrowth:    floatPtr(gb),
+			wantDaysUntil: floatPtr(50),
+		},
+		{
+			name:          "filling quickly over hours",
+			samples:       []diskSample{{0, 12 * gb}, {6 * time.Hour, 11 * gb}, {12 * time.Hour, 10 * gb}},
+			wantGrowth:    floatPtr(4 * gb),
+			wantDaysUntil: floatPtr(2.5),
+		},
+		{
+			name:       "space being freed",
+			samples:    []diskSample{{0, 40 * gb}, {2 * day, 44 * gb}},
+			wantGrowth: floatPtr(-2 * gb),
+		},
+		{
+			name:       "unchanged",
+			samples:    []diskSample{{0, 40 * gb}, {day, 40 * gb}, {2 * day, 40 * gb}},
+			wantGrowth: floatPtr(0),
+		},
+		{
+			// Used space of 10, 12 and 11 GB fits a line rising by half a GB a day
+			name:          "least-squares fit of noisy samples",
+			samples:       []diskSample{{0, 90 * gb}, {day, 88 * gb}, {2 * day, 89 * gb}},
+			wantGrowth:    floatPtr(0.5 * gb),
+			wantDaysUntil: floatPtr(178),
+		},
+		{
+			name:    "samples span less than an hour",
+			samples: []diskSample{{0, 50 * gb}, {30 * time.Minute, 40 * gb}},
+		},
+		{
+			name:    "a single sample",
+			samples: []diskSample{{0, 50 * gb}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []HistoryEntry
+			for _, s := range tt.samples {
+				entries = append(entries, HistoryEntry{
+					Time:  historyStart.Add(s.after),
+					Disks: map[string]HistoryDisk{"C:": {TotalBytes: diskTotal, FreeBytes: s.free}},
+				})
+			}
+
+			report := AnalyzeHistory(entries, historyStart)
+			if len(report.Disks) != 1 {
+				t.Fatalf("%d disk trends, want 1", len(report.Disks))
+			}
+			trend := report.Disks[0]
+			first, last := tt.samples[0], tt.samples[len(tt.samples)-1]
+			if trend.Samples != len(tt.samples) || trend.FirstFree != first.free || trend.LastFree != last.free || !trend.Last.Equal(historyStart.Add(last.after)) {
+				t.Errorf("trend %+v does not span the samples", trend)
+			}
+			if !approxEqual(trend.UsedGrowthPerDay, tt.wantGrowth) {
+				t.Errorf("growth per day %s, want %s", format(trend.UsedGrowthPerDay), format(tt.wantGrowth))
+			}
+			if !approxEqual(trend.DaysUntilFull, tt.wantDaysUntil) {
+				t.Errorf("days until full %s, want %s", format(trend.DaysUntilFull), format(tt.wantDaysUntil))
+			}
+		})
+	}
+}
+
+func TestAnalyzeHistorySummaries(t *testing.T) {
+	size := func(n int64) *int64 { return &n }
+	score := func(n int) *int { return &n }
+	entries := []HistoryEntry{
+		{Time: historyStart, TempFolderBytes: size(100), HealthScore: score(80),
+			Disks: map[string]HistoryDisk{"D:": {TotalBytes: diskTotal, FreeBytes: 10 * gb}, "C:": {TotalBytes: diskTotal, FreeBytes: 50 * gb}}},
+		{Time: historyStart.Add(time.Hour), Operations: []HistoryOperation{
+			{Operation: "temp", Status: StatusSuccess, BytesFreed: 60},
+			{Operation: "sfc", Status: StatusFailed},
+		}},
+		{Time: historyStart.Add(day), TempFolderBytes: size(300), HealthScore: score(90),
+			Disks: map[string]HistoryDisk{"C:": {TotalBytes: diskTotal, FreeBytes: 49 * gb}}},
+		{Time: historyStart.Add(day + time.Hour), Operations: []HistoryOperation{
+			{Operation: "temp", Status: StatusTimeout, BytesFreed: 40},
+		}},
+	}
+
+	report := AnalyzeHistory(entries, historyStart)
+
+	if report.Entries != 4 {
+		t.Errorf("%d entries, want 4", report.Entries)
+	}
+	var drives []string
+	for _, disk := range report.Disks {
+		drives = append(drives, disk.Drive)
+	}
+	if want := []string{"C:", "D:"}; !reflect.DeepEqual(drives, want) {
+		t.Errorf("drives %q, want %q", drives, want)
+	}
+	if d := report.Disks[1]; d.Samples != 1 || d.UsedGrowthPerDay != nil {
+		t.Errorf("D: trend %+v, want one sample and no growth", d)
+	}
+
+	if report.Temp == nil || report.Temp.Samples != 2 || report.Temp.FirstBytes != 100 || report.Temp.LastBytes != 300 || !approxEqual(report.Temp.GrowthPerDay, floatPtr(200)) {
+		t.Errorf("temp trend %+v, want 100 to 300 bytes growing 200 a day", report.Temp)
+	}
+
+	wantOps := []OperationHistory{
+		{Operation: "sfc", Runs: 1, Failures: 1, LastRun: historyStart.Add(time.Hour), LastStatus: StatusFailed},
+		{Operation: "temp", Runs: 2, Failures: 1, BytesFreed: 100, LastRun: historyStart.Add(day + time.Hour), LastStatus: StatusTimeout},
+	}
+	if !reflect.DeepEqual(report.Operations, wantOps) {
+		t.Errorf("operations %+v, want %+v", report.Operations, wantOps)
+	}
+
+	wantDays := []HistoryDay{
+		{Date: "2026-10-01", FreeBytes: map[string]uint64{"C:": 50 * gb, "D:": 10 * gb}, TempFolderBytes: size(100), HealthScore: score(80), OperationsRun: 2, OperationsFailed: 1},
+		{Date: "2026-10-02", FreeBytes: map[string]uint64{"C:": 49 * gb}, TempFolderBytes: size(300), HealthScore: score(90), OperationsRun: 1, OperationsFailed: 1},
+	}
+	if !reflect.DeepEqual(report.Days, wantDays) {
+		t.Errorf("days %+v, want %+v", report.Days, wantDays)
+	}
+}
+
+func TestAppendHistoryRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention HistoryRetention
+		// ages are how long before the last entry each earlier entry was recorded, oldest first
+		ages []time.Duration
+		want []string
+	}{
+		{name: "no limits", ages: []time.Duration{400 * day, 10 * day}, want: []string{"0", "1", "last"}},
+		{name: "max age", retention: HistoryRetention{MaxAge: 30 * day}, ages: []time.Duration{400 * day, 31 * day, 29 * day}, want: []string{"2", "last"}},
+		{name: "max entries", retention: HistoryRetention{MaxEntries: 2}, ages: []time.Duration{3 * day, 2 * day, day}, want: []string{"2", "last"}},
+		{name: "both limits", retention: HistoryRetention{MaxAge: 10 * day, MaxEntries: 3}, ages: []time.Duration{20 * day, 5 * day, 4 * day, 3 * day}, want: []string{"2", "3", "last"}},
+		{name: "within limits", retention: DefaultHistoryRetention, ages: []time.Duration{300 * day, day}, want: []string{"0", "1", "last"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			now := time.Now()
+			for i, age := range tt.ages {
+				entry := &HistoryEntry{Time: now.Add(-age), RunID: strconv.Itoa(i)}
+				if err := AppendHistory(dir, entry, HistoryRetention{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := AppendHistory(dir, &HistoryEntry{Time: now, RunID: "last"}, tt.retention); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := LoadHistory(dir, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.RunID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Concurrent writers each append and prune; no entry may be lost to another's prune
+func TestAppendHistoryConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	retention := HistoryRetention{MaxAge: 30 * day}
+	const writers, appends = 8, 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*appends)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range appends {
+				// Every other entry is already expired, so the next append rewrites the file
+				entry := &HistoryEntry{Time: time.Now(), RunID: fmt.Sprintf("%d-%d", w, i)}
+				if i%2 == 1 {
+					entry.Time = entry.Time.Add(-400 * day)
+				}
+				errs <- AppendHistory(dir, entry, retention)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := LoadHistory(dir, time.Now().Add(-day))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		seen[entry.RunID] = true
+	}
+	for w := range writers {
+		for i := 0; i < appends; i += 2 {
+			if id := fmt.Sprintf("%d-%d", w, i); !seen[id] {
+				t.Errorf("entry %s was lost", id)
+			}
+		}
+	}
+}
+
+// Lines cut short by a crash are skipped when reading and dropped when pruning
+func TestHistoryToleratesDamagedLines(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	if err := AppendHistory(dir, &HistoryEntry{Time: now.Add(-time.Hour), RunID: "first"}, HistoryRetention{}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, historyFile), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2026-10`)
+	f.WriteString("\n")
+	f.Close()
+
+	entries, err := LoadHistory(dir, time.Time{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("loaded %+v, %v; want the one readable entry", entries, err)
+	}
+	if err := AppendHistory(dir, &HistoryEntry{Time: now, RunID: "second"}, DefaultHistoryRetention); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, historyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := len(splitLines(string(data))); lines != 2 {
+		t.Errorf("history has %d lines after pruning, want 2", lines)
+	}
+}
+
+func TestHistoryRetentionValidate(t *testing.T) {
+	tests := []struct {
+		retention HistoryRetention
+		wantErr   bool
+	}{
+		{retention: HistoryRetention{}},
+		{retention: DefaultHistoryRetention},
+		{retention: HistoryRetention{MaxAge: -time.Hour}, wantErr: true},
+		{retention: HistoryRetention{MaxEntries: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.retention.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() = %v, want error: %v", tt.retention, err, tt.wantErr)
+		}
+	}
+}
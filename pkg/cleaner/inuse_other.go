@@ -0,0 +1,13 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isFileInUse reports whether err means the file is busy; only meaningful on Windows
+func isFileInUse(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.ETXTBSY)
+}
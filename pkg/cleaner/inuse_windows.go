@@ -0,0 +1,16 @@
+//go:build windows
+
+package cleaner
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION, returned when another process holds the file open
+const errorSharingViolation = syscall.Errno(32)
+
+// isFileInUse reports whether err means the file is locked by another process
+func isFileInUse(err error) bool {
+	return errors.Is(err, errorSharingViolation)
+}
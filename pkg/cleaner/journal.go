@@ -0,0 +1,231 @@
+package cleaner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of change recorded in a change journal
+const (
+	ChangeRegistry    = "registry"
+	ChangeRegistryKey = "registry_key"
+	ChangePowerScheme = "power_scheme"
+)
+
+// Change records one system setting modified by wincleaner and the value it replaced
+type Change struct {
+	Kind string    `json:"kind" yaml:"kind"`
+	Time time.Time `json:"time" yaml:"time"`
+	// Path and Name identify a registry value (e.g. HKCU:\Software\...\Personalize, EnableTransparency);
+	// for a registry_key change Path is the key that was created
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Previous is the value before the change; nil means it did not exist
+	Previous *RegistryValue `json:"previous" yaml:"previous"`
+	// New is the value written; nil means the value was deleted
+	New *RegistryValue `json:"new" yaml:"new"`
+	// PreviousScheme is the power scheme GUID active before a power scheme change
+	PreviousScheme string `json:"previous_scheme,omitempty" yaml:"previous_scheme,omitempty"`
+}
+
+// ChangeJournal is the persisted record of the settings changed by one run
+type ChangeJournal struct {
+	mu sync.Mutex
+
+	RunID      string     `json:"run_id" yaml:"run_id"`
+	Created    time.Time  `json:"created" yaml:"created"`
+	RolledBack *time.Time `json:"rolled_back,omitempty" yaml:"rolled_back,omitempty"`
+	Changes    []Change   `json:"changes" yaml:"changes"`
+
+	dir    string
+	opened bool
+}
+
+// NewChangeJournal returns the journal for a run, stored in dir; nothing is written
+// until the first change is recorded
+func NewChangeJournal(dir, runID string) *ChangeJournal {
+	return &ChangeJournal{RunID: runID, Created: time.Now(), dir: dir}
+}
+
+// Record appends a change and persists the journal
+func (j *ChangeJournal) Record(change Change) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Several runs may share a run ID, so keep the changes an earlier one journaled
+	if !j.opened {
+		j.opened = true
+		if existing, err := LoadChangeJournal(j.dir, j.RunID); err == nil {
+			j.Created = existing.Created
+			j.Changes = append(existing.Changes, j.Changes...)
+		}
+	}
+	change.Time = time.Now()
+	j.Changes = append(j.Changes, change)
+	return j.save()
+}
+
+// Recorded returns how many changes the journal holds
+func (j *ChangeJournal) Recorded() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.Changes)
+}
+
+// save writes the journal atomically; callers hold j.mu
+func (j *ChangeJournal) save() error {
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		return fmt.Errorf("creating journal directory: %w", err)
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(j.dir, j.RunID+".json")
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// LoadChangeJournal reads a run's journal from dir. A runID of "" or "latest" selects
+// the most recent journal that has not been rolled back.
+func LoadChangeJournal(dir, runID string) (*ChangeJournal, error) {
+	if runID == "" || runID == "latest" {
+		journals, err := ListChangeJournals(dir)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(journals) - 1; i >= 0; i-- {
+			if journals[i].RolledBack == nil {
+				return journals[i], nil
+			}
+		}
+		return nil, errors.New("no change journal to roll back")
+	}
+
+	if err := ValidateRunID(runID); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, runID+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("change journal %q not found: %w", runID, err)
+	}
+	j := &ChangeJournal{dir: dir}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("corrupt change journal %q: %w", runID, err)
+	}
+	return j, nil
+}
+
+// ListChangeJournals returns every journal in dir, oldest first
+func ListChangeJournals(dir string) ([]*ChangeJournal, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var journals []*ChangeJournal
+	for _, entry := range entries {
+		runID, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		j, err := LoadChangeJournal(dir, runID)
+		if err != nil {
+			continue
+		}
+		journals = append(journals, j)
+	}
+	sort.Slice(journals, func(i, k int) bool { return journals[i].Created.Before(journals[k].Created) })
+	return journals, nil
+}
+
+// RollbackChanges restores every setting recorded in a run's journal to its previous
+// state, newest change first, and marks the journal as rolled back
+func RollbackChanges(ctx context.Context, opts Options, dir, runID string) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	j, err := LoadChangeJournal(dir, runID)
+	if err != nil {
+		return res, err
+	}
+	if j.RolledBack != nil {
+		return res, fmt.Errorf("run %s was already rolled back on %s", j.RunID, j.RolledBack.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(opts.Writer(), "Rolling back %d changes from run %s...\n", len(j.Changes), j.RunID)
+
+	// The rollback itself must not be journaled
+	opts.Journal = nil
+	var failures []string
+	for i := len(j.Changes) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		change := j.Changes[i]
+		if err := revertChange(ctx, opts, res, change); err != nil {
+			failures = append(failures, change.describe()+": "+err.Error())
+			res.skip(change.describe(), err.Error())
+			continue
+		}
+		res.ItemsRemoved++
+	}
+	if len(failures) > 0 {
+		return res, fmt.Errorf("%d of %d changes could not be rolled back:\n%s", len(failures), len(j.Changes), strings.Join(failures, "\n"))
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.RolledBack = &now
+	return res, j.save()
+}
+
+// revertChange restores the state recorded before one change
+func revertChange(ctx context.Context, opts Options, res *OperationResult, change Change) error {
+	switch change.Kind {
+	case ChangeRegistry:
+		if change.Previous == nil {
+			return deleteRegistryValue(ctx, opts, res, change.Path, change.Name)
+		}
+		return setRegistryValue(ctx, opts, res, change.Path, change.Name, change.Previous)
+	case ChangeRegistryKey:
+		return deleteRegistryKey(ctx, opts, res, change.Path)
+	case ChangePowerScheme:
+		if change.PreviousScheme == "" {
+			return nil
+		}
+		return setPowerScheme(ctx, opts, res, change.PreviousScheme)
+	default:
+		return fmt.Errorf("unknown change kind %q", change.Kind)
+	}
+}
+
+// describe names the setting a change applies to
+func (c Change) describe() string {
+	switch c.Kind {
+	case ChangePowerScheme:
+		return "active power scheme"
+	case ChangeRegistryKey:
+		return c.Path
+	}
+	return c.Path + `\` + c.Name
+}
@@ -0,0 +1,240 @@
+package cleaner
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// useFakeRegistry replaces WinRegistry with an empty FakeRegistry for the duration of the test
+func useFakeRegistry(t *testing.T) *FakeRegistry {
+	t.Helper()
+	saved := WinRegistry
+	t.Cleanup(func() { WinRegistry = saved })
+	reg := NewFakeRegistry()
+	WinRegistry = reg
+	return reg
+}
+
+// registrySnapshot lists the keys and values of a FakeRegistry, sorted
+func registrySnapshot(f *FakeRegistry) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var entries []string
+	for key := range f.keys {
+		entries = append(entries, key+`\`)
+	}
+	for name, value := range f.values {
+		entries = append(entries, name+" = "+value.Display())
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+const (
+	personalizeKey = `HKCU:\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`
+	serializeKey   = `HKCU:\Software\Microsoft\Windows\CurrentVersion\Explorer\Serialize`
+	testRunID      = "20261017-120000"
+)
+
+func TestRollbackChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(reg *FakeRegistry)
+		profile OptimalProfile
+		// wantKinds lists the kinds of the journaled changes, in order
+		wantKinds []string
+		// wantRun lists the commands run by the rollback
+		wantRun []string
+	}{
+		{
+			name:      "a changed value is restored",
+			setup:     func(reg *FakeRegistry) { reg.Put(personalizeKey, "EnableTransparency", DWORDValue(1)) },
+			profile:   OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable}},
+			wantKinds: []string{ChangeRegistry},
+		},
+		{
+			name:      "a new value is deleted",
+			setup:     func(reg *FakeRegistry) { reg.EnsureKey(context.Background(), personalizeKey) },
+			profile:   OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable}},
+			wantKinds: []string{ChangeRegistry},
+		},
+		{
+			name:      "a deleted value is recreated",
+			setup:     func(reg *FakeRegistry) { reg.Put(serializeKey, "StartupDelayInMSec", DWORDValue(500)) },
+			profile:   OptimalProfile{Tweaks: map[string]string{"startup_delay": TweakDisable}},
+			wantKinds: []string{ChangeRegistry},
+		},
+		{
+			name: "a created key is removed",
+			setup: func(reg *FakeRegistry) {
+				reg.EnsureKey(context.Background(), `HKCU:\Software\Microsoft\Windows\CurrentVersion\Themes`)
+			},
+			profile:   OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable}},
+			wantKinds: []string{ChangeRegistryKey, ChangeRegistry},
+		},
+		{
+			name:    "created parent keys are removed, existing ones kept",
+			setup:   func(reg *FakeRegistry) { reg.Put(`HKCU:\Software`, "Other", StringValue("kept")) },
+			profile: OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable, "startup_delay": TweakEnable}},
+			wantKinds: []string{
+				ChangeRegistryKey, ChangeRegistryKey, ChangeRegistryKey, ChangeRegistryKey, ChangeRegistryKey, ChangeRegistry,
+				ChangeRegistryKey, ChangeRegistryKey, ChangeRegistry,
+			},
+		},
+		{
+			name:      "the power scheme is restored",
+			setup:     func(reg *FakeRegistry) {},
+			profile:   OptimalProfile{PowerPlan: "high_performance"},
+			wantKinds: []string{ChangePowerScheme},
+			wantRun:   []string{"powershell -Command powercfg /setactive 381b4222-f694-41f0-9685-ff5bb260df2e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := useFakeRegistry(t)
+			fake := useFakeRunner(t)
+			fake.On(CommandResult{Stdout: []byte("Power Scheme GUID: 381b4222-f694-41f0-9685-ff5bb260df2e  (Balanced)")}, "powercfg", "/getactivescheme")
+			tt.setup(reg)
+			before := registrySnapshot(reg)
+			dir := t.TempDir()
+
+			journal := NewChangeJournal(dir, testRunID)
+			if _, err := ApplyOptimalProfile(context.Background(), Options{Out: io.Discard, Journal: journal}, tt.profile); err != nil {
+				t.Fatal(err)
+			}
+			var kinds []string
+			for _, change := range journal.Changes {
+				kinds = append(kinds, change.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.wantKinds) {
+				t.Errorf("journaled %q, want %q", kinds, tt.wantKinds)
+			}
+
+			fake.Calls = nil
+			res, err := RollbackChanges(context.Background(), Options{Out: io.Discard}, dir, "latest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.ItemsRemoved != len(tt.wantKinds) {
+				t.Errorf("reverted %d changes, want %d", res.ItemsRemoved, len(tt.wantKinds))
+			}
+			if after := registrySnapshot(reg); !reflect.DeepEqual(after, before) {
+				t.Errorf("registry after rollback %q, want %q", after, before)
+			}
+			if !reflect.DeepEqual(fake.Calls, tt.wantRun) {
+				t.Errorf("ran %q, want %q", fake.Calls, tt.wantRun)
+			}
+
+			loaded, err := LoadChangeJournal(dir, testRunID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.RolledBack == nil {
+				t.Errorf("the journal is not marked as rolled back")
+			}
+			if _, err := RollbackChanges(context.Background(), Options{Out: io.Discard}, dir, testRunID); err == nil || !strings.Contains(err.Error(), "already rolled back") {
+				t.Errorf("second rollback error %v, want already rolled back", err)
+			}
+		})
+	}
+}
+
+// A key that has gained other values since it was created is left in place
+func TestRollbackKeepsKeysInUse(t *testing.T) {
+	reg := useFakeRegistry(t)
+	useFakeRunner(t)
+	dir := t.TempDir()
+	journal := NewChangeJournal(dir, testRunID)
+	profile := OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable}}
+	if _, err := ApplyOptimalProfile(context.Background(), Options{Out: io.Discard, Journal: journal}, profile); err != nil {
+		t.Fatal(err)
+	}
+	reg.Put(personalizeKey, "AppsUseLightTheme", DWORDValue(1))
+
+	res, err := RollbackChanges(context.Background(), Options{Out: io.Discard}, dir, testRunID)
+	if err == nil || !strings.Contains(err.Error(), ErrKeyNotEmpty.Error()) {
+		t.Fatalf("error %v, want %v", err, ErrKeyNotEmpty)
+	}
+	if len(res.ItemsSkipped) == 0 || res.ItemsSkipped[0].Path != personalizeKey {
+		t.Errorf("skipped %+v, want %s first", res.ItemsSkipped, personalizeKey)
+	}
+	if value, _ := reg.GetValue(context.Background(), personalizeKey, "AppsUseLightTheme"); !value.Equal(DWORDValue(1)) {
+		t.Errorf("a value the run did not write was removed")
+	}
+	if value, _ := reg.GetValue(context.Background(), personalizeKey, "EnableTransparency"); value != nil {
+		t.Errorf("the journaled value %s was not removed", value.Display())
+	}
+	if loaded, _ := LoadChangeJournal(dir, testRunID); loaded.RolledBack != nil {
+		t.Errorf("a failed rollback marked the journal as rolled back")
+	}
+}
+
+func TestRollbackDryRun(t *testing.T) {
+	reg := useFakeRegistry(t)
+	useFakeRunner(t)
+	dir := t.TempDir()
+	journal := NewChangeJournal(dir, testRunID)
+	profile := OptimalProfile{Tweaks: map[string]string{"transparency": TweakEnable}}
+	if _, err := ApplyOptimalProfile(context.Background(), Options{Out: io.Discard, Journal: journal}, profile); err != nil {
+		t.Fatal(err)
+	}
+	applied := registrySnapshot(reg)
+
+	res, err := RollbackChanges(context.Background(), Options{DryRun: true, Out: io.Discard}, dir, testRunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := registrySnapshot(reg); !reflect.DeepEqual(after, applied) {
+		t.Errorf("a dry-run rollback changed the registry: %q, want %q", after, applied)
+	}
+	if len(res.Commands) != len(journal.Changes) {
+		t.Errorf("reported %d changes, want %d", len(res.Commands), len(journal.Changes))
+	}
+	if loaded, _ := LoadChangeJournal(dir, testRunID); loaded.RolledBack != nil {
+		t.Errorf("a dry-run rollback marked the journal as rolled back")
+	}
+}
+
+func TestValidateRunID(t *testing.T) {
+	tests := []struct {
+		runID string
+		valid bool
+	}{
+		{runID: "20261017-120000", valid: true},
+		{runID: "custom-run", valid: true},
+		{runID: ""},
+		{runID: "."},
+		{runID: ".."},
+		{runID: "../20261017-120000"},
+		{runID: `..\20261017-120000`},
+		{runID: "/etc/passwd"},
+		{runID: `C:\Windows`},
+		{runID: "a/b"},
+	}
+	for _, tt := range tests {
+		if err := ValidateRunID(tt.runID); (err == nil) != tt.valid {
+			t.Errorf("ValidateRunID(%q) = %v, want valid: %v", tt.runID, err, tt.valid)
+		}
+	}
+}
+
+// Run IDs from the command line cannot reach outside the journal and quarantine stores
+func TestStoresRejectEscapingRunIDs(t *testing.T) {
+	dir := t.TempDir()
+	for _, runID := range []string{"..", "../journal", `..\journal`} {
+		if _, err := LoadChangeJournal(dir, runID); err == nil || !strings.Contains(err.Error(), "invalid run ID") {
+			t.Errorf("LoadChangeJournal(%q) error %v, want invalid run ID", runID, err)
+		}
+		if _, err := RestoreQuarantine(context.Background(), Options{Out: io.Discard}, dir, runID); err == nil || !strings.Contains(err.Error(), "invalid run ID") {
+			t.Errorf("RestoreQuarantine(%q) error %v, want invalid run ID", runID, err)
+		}
+	}
+	if _, err := LoadChangeJournal(dir, "20261017-120000"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing journal error %v, want not found", err)
+	}
+}
@@ -1,122 +1,115 @@
 package cleaner
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
 // RunDiskOptimization runs appropriate optimization based on drive type (defrag for HDDs, TRIM for SSDs)
-func RunDiskOptimization(verbose bool) error {
-	checkCmd := exec.Command("powershell", "-Command", "Get-PhysicalDisk | Select-Object DeviceId, MediaType | ConvertTo-Json")
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: powershell -Command Get-PhysicalDisk | Select-Object DeviceId, MediaType | ConvertTo-Json")
-	}
-	output, err := checkCmd.Output()
+func RunDiskOptimization(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	result, err := queryCommand(ctx, opts, res, "powershell", "-Command", "Get-PhysicalDisk | Select-Object DeviceId, MediaType | ConvertTo-Json")
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	// Parse output to determine drive types
 	// Simple check - if any SSD is found, use /O which automatically selects the correct optimization
-	if strings.Contains(string(output), "SSD") {
-		if verbose {
-			fmt.Println("[VERBOSE] Running command: defrag /C /O /U /V")
-		}
+	if strings.Contains(string(result.Stdout), "SSD") {
 		// Use /O which will automatically select proper optimization method based on media type
-		cmd := exec.Command("defrag", "/C", "/O", "/U", "/V")
-		return cmd.Run()
-	} else {
-		if verbose {
-			fmt.Println("[VERBOSE] Running command: defrag /C /D /U /V")
-		}
-		// Traditional defrag for HDDs
-		cmd := exec.Command("defrag", "/C", "/D", "/U", "/V")
-		return cmd.Run()
+		_, err = runCommand(ctx, opts, res, "defrag", "/C", "/O", "/U", "/V")
+		return res, err
 	}
+	// Traditional defrag for HDDs
+	_, err = runCommand(ctx, opts, res, "defrag", "/C", "/D", "/U", "/V")
+	return res, err
 }
 
 // RunCheckDisk runs the Windows Check Disk utility
-func RunCheckDisk(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: chkdsk /f /r /c")
-	}
+func RunCheckDisk(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
 	// Schedule CHKDSK to run on next boot since it requires exclusive access
-	cmd := exec.Command("chkdsk", "/f", "/r", "/c")
-	return cmd.Run()
+	_, err := runCommand(ctx, opts, res, "chkdsk", "/f", "/r", "/c")
+	res.RebootRequired = err == nil && !opts.DryRun
+	return res, err
 }
 
 // FlushDNSCache flushes the Windows DNS resolver cache
-func FlushDNSCache(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: ipconfig /flushdns")
-	}
-	cmd := exec.Command("ipconfig", "/flushdns")
-	return cmd.Run()
+func FlushDNSCache(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	_, err := runCommand(ctx, opts, res, "ipconfig", "/flushdns")
+	return res, err
 }
 
 // RunMemoryDiagnostic runs the Windows Memory Diagnostic tool
-func RunMemoryDiagnostic(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: mdsched")
-	}
-	cmd := exec.Command("mdsched")
-	return cmd.Run()
+func RunMemoryDiagnostic(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	// The diagnostic runs during the next restart
+	_, err := runCommand(ctx, opts, res, "mdsched")
+	res.RebootRequired = err == nil && !opts.DryRun
+	return res, err
 }
 
 // OptimizePowerConfig optimizes Windows power settings
-func OptimizePowerConfig(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: powercfg /setactive SCHEME_BALANCED")
-	}
+func OptimizePowerConfig(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
 	// Reset power scheme to balanced
-	cmd := exec.Command("powercfg", "/setactive", "SCHEME_BALANCED")
-	return cmd.Run()
+	_, err := runCommand(ctx, opts, res, "powercfg", "/setactive", "SCHEME_BALANCED")
+	return res, err
 }
 
 // CleanPrefetch cleans the Windows prefetch directory
-func CleanPrefetch(verbose bool) error {
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: powershell -Command Remove-Item -Path $env:SystemRoot\\Prefetch\\* -Force -ErrorAction SilentlyContinue")
-	}
+func CleanPrefetch(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
 	// Using PowerShell to clean prefetch directory with proper error handling
-	cmd := exec.Command("powershell", "-Command",
+	_, err := runCommand(ctx, opts, res, "powershell", "-Command",
 		"Remove-Item -Path \"$env:SystemRoot\\Prefetch\\*\" -Force -ErrorAction SilentlyContinue")
-	return cmd.Run()
+	return res, err
 }
 
 // ResetNetworkConfig resets Windows network configuration
-func ResetNetworkConfig(verbose bool) error {
-	var errors []string
+func ResetNetworkConfig(ctx context.Context, opts Options) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
 
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: netsh winsock reset")
-	}
-	cmd := exec.Command("netsh", "winsock", "reset")
-	out, err := cmd.CombinedOutput()
+	var failures []string
+
+	result, err := runCommand(ctx, opts, res, "netsh", "winsock", "reset")
 	if err != nil {
-		if verbose {
-			errors = append(errors, fmt.Sprintf("netsh winsock reset failed: %v\nOutput: %s", err, string(out)))
+		if opts.Verbose {
+			failures = append(failures, fmt.Sprintf("netsh winsock reset failed: %v\nOutput: %s", err, string(result.CombinedOutput())))
 		} else {
-			errors = append(errors, "Partial success: 'netsh winsock reset' failed. You can try running this command manually in an elevated command prompt: netsh winsock reset")
+			failures = append(failures, "Partial success: 'netsh winsock reset' failed. You can try running this command manually in an elevated command prompt: netsh winsock reset")
 		}
 	}
 
-	if verbose {
-		fmt.Println("[VERBOSE] Running command: netsh int ip reset")
-	}
-	cmd = exec.Command("netsh", "int", "ip", "reset")
-	out, err = cmd.CombinedOutput()
+	result, err = runCommand(ctx, opts, res, "netsh", "int", "ip", "reset")
 	if err != nil {
-		if verbose {
-			errors = append(errors, fmt.Sprintf("netsh int ip reset failed: %v\nOutput: %s", err, string(out)))
+		if opts.Verbose {
+			failures = append(failures, fmt.Sprintf("netsh int ip reset failed: %v\nOutput: %s", err, string(result.CombinedOutput())))
 		} else {
-			errors = append(errors, "Partial success: 'netsh int ip reset' failed. You can try running this command manually in an elevated command prompt: netsh int ip reset")
+			failures = append(failures, "Partial success: 'netsh int ip reset' failed. You can try running this command manually in an elevated command prompt: netsh int ip reset")
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf(strings.Join(errors, "\n"))
+	// Winsock and TCP/IP resets only take effect after a restart
+	res.RebootRequired = len(failures) < 2 && !opts.DryRun
+	if len(failures) > 0 {
+		return res, errors.New(strings.Join(failures, "\n"))
 	}
-	return nil
+	return res, nil
 }
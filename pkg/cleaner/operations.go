@@ -0,0 +1,100 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Operation describes a single maintenance operation. The CLI commands, the
+// interactive menu, `all` and default_ops are all derived from this registry.
+type Operation struct {
+	// ID is the command name, e.g. "disk"
+	ID string
+	// Name is the display name used in output and per-operation config timeouts
+	Name string
+	// Description is a one-line summary shown in help text and menus
+	Description string
+	// Timeout is the default timeout; zero means no timeout
+	Timeout time.Duration
+	// AllTimeout replaces Timeout as the default when the operation runs as part of
+	// `all`; zero means Timeout
+	AllTimeout time.Duration
+	// RequiresAdmin marks operations that need administrator privileges
+	RequiresAdmin bool
+	// Destructive marks operations that delete data or reset configuration
+	Destructive bool
+	// Run performs the operation
+	Run func(ctx context.Context, opts Options) (*OperationResult, error)
+}
+
+// Execute runs the operation and labels its result with the operation's ID and name
+func (op Operation) Execute(ctx context.Context, opts Options) (*OperationResult, error) {
+	res, err := op.Run(ctx, opts)
+	if res == nil {
+		res = newResult(opts)
+		res.finish()
+	}
+	res.Operation = op.ID
+	res.Name = op.Name
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res, err
+}
+
+var operations = []Operation{
+	{ID: "disk", Name: "Disk Cleanup", Description: "Run Disk Cleanup utility", Destructive: true, Run: RunDiskCleanup},
+	{ID: "temp", Name: "Temporary Files Cleaning", Description: "Clean temporary files", Destructive: true, Run: CleanTempFiles},
+	{ID: "events", Name: "Event Logs Clearing", Description: "Clear Windows event logs", RequiresAdmin: true, Destructive: true, Run: ClearEventLogs},
+	{ID: "sfc", Name: "System File Checker", Description: "Run System File Checker", Timeout: 1000 * time.Second, AllTimeout: 120 * time.Second, RequiresAdmin: true, Run: RunSystemFileChecker},
+	{ID: "dism", Name: "DISM Windows Image Repair", Description: "Run DISM to repair Windows image", Timeout: 1000 * time.Second, AllTimeout: 180 * time.Second, RequiresAdmin: true, Run: RunDISM},
+	{ID: "recycle", Name: "Empty Recycle Bin", Description: "Empty Recycle Bin", Destructive: true, Run: EmptyRecycleBin},
+	{ID: "optimize", Name: "Disk Optimization", Description: "Run Disk Optimization (defrag for HDDs, TRIM for SSDs)", RequiresAdmin: true, Run: RunDiskOptimization},
+	{ID: "chkdsk", Name: "Check Disk", Description: "Run Check Disk utility", Timeout: 1000 * time.Second, AllTimeout: 90 * time.Second, RequiresAdmin: true, Run: RunCheckDisk},
+	{ID: "flushdns", Name: "Flush DNS Cache", Description: "Flush DNS resolver cache", Run: FlushDNSCache},
+	{ID: "memcheck", Name: "Windows Memory Diagnostic", Description: "Run Windows Memory Diagnostic tool", RequiresAdmin: true, Run: RunMemoryDiagnostic},
+	{ID: "prefetch", Name: "Clean Prefetch Cache", Description: "Clean Windows prefetch directory", RequiresAdmin: true, Destructive: true, Run: CleanPrefetch},
+	{ID: "power", Name: "Optimize Power Configuration", Description: "Optimize power configuration settings", Run: OptimizePowerConfig},
+	{ID: "resetnet", Name: "Reset Network Configuration", Description: "Reset Windows network configuration", RequiresAdmin: true, Destructive: true, Run: ResetNetworkConfig},
+}
+
+// Operations returns the registered operations in execution order
+func Operations() []Operation {
+	return append([]Operation(nil), operations...)
+}
+
+// LookupOperation finds a registered operation by ID
+func LookupOperation(id string) (Operation, bool) {
+	for _, op := range operations {
+		if op.ID == id {
+			return op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// operationID is the form of an operation ID, which doubles as its command name
+var operationID = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// ValidateID checks that id can be used as an operation ID and command name
+func ValidateID(id string) error {
+	if !operationID.MatchString(id) {
+		return fmt.Errorf("id %q may only contain lowercase letters, digits, '-' and '_'", id)
+	}
+	return nil
+}
+
+// RegisterOperation adds an operation, such as a custom cleanup target, to the registry.
+// IDs must be well formed and unique across built-in and registered operations.
+func RegisterOperation(op Operation) error {
+	if err := ValidateID(op.ID); err != nil {
+		return err
+	}
+	if _, exists := LookupOperation(op.ID); exists {
+		return fmt.Errorf("operation %q is already registered", op.ID)
+	}
+	operations = append(operations, op)
+	return nil
+}
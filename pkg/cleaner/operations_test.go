@@ -0,0 +1,248 @@
+package cleaner
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFakeRunner replaces Runner with a FakeRunner for the duration of the test
+func useFakeRunner(t *testing.T) *FakeRunner {
+	t.Helper()
+	saved := Runner
+	t.Cleanup(func() { Runner = saved })
+	fake := NewFakeRunner()
+	Runner = fake
+	return fake
+}
+
+const (
+	diskQuery     = "powershell -Command Get-PhysicalDisk | Select-Object DeviceId, MediaType | ConvertTo-Json"
+	prefetchClean = `powershell -Command Remove-Item -Path "$env:SystemRoot\Prefetch\*" -Force -ErrorAction SilentlyContinue`
+)
+
+func TestCommandOperations(t *testing.T) {
+	failed := CommandResult{ExitCode: 1, Stderr: []byte("access denied")}
+
+	tests := []struct {
+		name string
+		id   string
+		// responses maps command lines to their canned results
+		responses map[string]CommandResult
+		dryRun    bool
+		// wantRun lists the command lines passed to Runner, in order
+		wantRun []string
+		// wantReported lists the command lines only reported in dry-run mode
+		wantReported []string
+		wantErr      bool
+		wantReboot   bool
+	}{
+		{name: "disk", id: "disk", wantRun: []string{"cleanmgr /sageset:102", "cleanmgr /sagerun:102"}},
+		{name: "disk stops after a failed sageset", id: "disk",
+			responses: map[string]CommandResult{"cleanmgr /sageset:102": failed},
+			wantRun:   []string{"cleanmgr /sageset:102"}, wantErr: true},
+		{name: "disk dry run", id: "disk", dryRun: true,
+			wantReported: []string{"cleanmgr /sageset:102", "cleanmgr /sagerun:102"}},
+		{name: "events", id: "events",
+			responses: map[string]CommandResult{"wevtutil el": {Stdout: []byte("Application\r\nSystem\r\n\r\nSetup\r\n")}},
+			wantRun:   []string{"wevtutil el", "wevtutil cl Application", "wevtutil cl System", "wevtutil cl Setup"}},
+		{name: "events ignores protected logs", id: "events",
+			responses: map[string]CommandResult{
+				"wevtutil el":          {Stdout: []byte("Security\nSystem\n")},
+				"wevtutil cl Security": failed,
+			},
+			wantRun: []string{"wevtutil el", "wevtutil cl Security", "wevtutil cl System"}},
+		{name: "events dry run still lists the logs", id: "events", dryRun: true,
+			responses:    map[string]CommandResult{"wevtutil el": {Stdout: []byte("Application\nSystem\n")}},
+			wantRun:      []string{"wevtutil el"},
+			wantReported: []string{"wevtutil cl Application", "wevtutil cl System"}},
+		{name: "events fails when the logs cannot be listed", id: "events",
+			responses: map[string]CommandResult{"wevtutil el": failed},
+			wantRun:   []string{"wevtutil el"}, wantErr: true},
+		{name: "sfc", id: "sfc", wantRun: []string{"sfc /scannow"}},
+		{name: "sfc failure", id: "sfc",
+			responses: map[string]CommandResult{"sfc /scannow": failed},
+			wantRun:   []string{"sfc /scannow"}, wantErr: true},
+		{name: "dism", id: "dism", wantRun: []string{"DISM /Online /Cleanup-Image /RestoreHealth"}},
+		{name: "recycle", id: "recycle", wantRun: []string{"powershell -Command Clear-RecycleBin -Force"}},
+		{name: "optimize trims SSDs", id: "optimize",
+			responses: map[string]CommandResult{diskQuery: {Stdout: []byte(`[{"DeviceId":"0","MediaType":"SSD"}]`)}},
+			wantRun:   []string{diskQuery, "defrag /C /O /U /V"}},
+		{name: "optimize defragments HDDs", id: "optimize",
+			responses: map[string]CommandResult{diskQuery: {Stdout: []byte(`[{"DeviceId":"0","MediaType":"HDD"}]`)}},
+			wantRun:   []string{diskQuery, "defrag /C /D /U /V"}},
+		{name: "optimize dry run still queries the disks", id: "optimize", dryRun: true,
+			responses:    map[string]CommandResult{diskQuery: {Stdout: []byte(`[{"DeviceId":"0","MediaType":"SSD"}]`)}},
+			wantRun:      []string{diskQuery},
+			wantReported: []string{"defrag /C /O /U /V"}},
+		{name: "optimize fails when the disks cannot be queried", id: "optimize",
+			responses: map[string]CommandResult{diskQuery: failed},
+			wantRun:   []string{diskQuery}, wantErr: true},
+		{name: "chkdsk", id: "chkdsk", wantRun: []string{"chkdsk /f /r /c"}, wantReboot: true},
+		{name: "chkdsk failure needs no reboot", id: "chkdsk",
+			responses: map[string]CommandResult{"chkdsk /f /r /c": failed},
+			wantRun:   []string{"chkdsk /f /r /c"}, wantErr: true},
+		{name: "chkdsk dry run needs no reboot", id: "chkdsk", dryRun: true,
+			wantReported: []string{"chkdsk /f /r /c"}},
+		{name: "flushdns", id: "flushdns", wantRun: []string{"ipconfig /flushdns"}},
+		{name: "memcheck", id: "memcheck", wantRun: []string{"mdsched"}, wantReboot: true},
+		{name: "prefetch", id: "prefetch", wantRun: []string{prefetchClean}},
+		{name: "power", id: "power", wantRun: []string{"powercfg /setactive SCHEME_BALANCED"}},
+		{name: "resetnet", id: "resetnet",
+			wantRun: []string{"netsh winsock reset", "netsh int ip reset"}, wantReboot: true},
+		{name: "resetnet partial failure", id: "resetnet",
+			responses: map[string]CommandResult{"netsh winsock reset": failed},
+			wantRun:   []string{"netsh winsock reset", "netsh int ip reset"}, wantErr: true, wantReboot: true},
+		{name: "resetnet total failure", id: "resetnet",
+			responses: map[string]CommandResult{"netsh winsock reset": failed, "netsh int ip reset": failed},
+			wantRun:   []string{"netsh winsock reset", "netsh int ip reset"}, wantErr: true},
+		{name: "resetnet dry run", id: "resetnet", dryRun: true,
+			wantReported: []string{"netsh winsock reset", "netsh int ip reset"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeRunner(t)
+			for line, result := range tt.responses {
+				fake.Responses[line] = result
+			}
+			op, ok := LookupOperation(tt.id)
+			if !ok {
+				t.Fatalf("operation %q is not registered", tt.id)
+			}
+
+			res, err := op.Execute(context.Background(), Options{DryRun: tt.dryRun, Out: io.Discard})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(fake.Calls, tt.wantRun) {
+				t.Errorf("ran %q, want %q", fake.Calls, tt.wantRun)
+			}
+			var reported []string
+			for _, cmd := range res.Commands {
+				if cmd.DryRun {
+					reported = append(reported, cmd.Command)
+				}
+			}
+			if !reflect.DeepEqual(reported, tt.wantReported) {
+				t.Errorf("reported %q, want %q", reported, tt.wantReported)
+			}
+			if len(res.Commands) != len(tt.wantRun)+len(tt.wantReported) {
+				t.Errorf("recorded %d commands, want %d", len(res.Commands), len(tt.wantRun)+len(tt.wantReported))
+			}
+			if res.RebootRequired != tt.wantReboot {
+				t.Errorf("reboot required %v, want %v", res.RebootRequired, tt.wantReboot)
+			}
+			if res.Operation != tt.id || res.DryRun != tt.dryRun {
+				t.Errorf("result labeled %q (dry run %v), want %q (dry run %v)", res.Operation, res.DryRun, tt.id, tt.dryRun)
+			}
+			if tt.wantErr && res.Error == "" {
+				t.Errorf("the result does not record the error")
+			}
+		})
+	}
+}
+
+// The failed command's output is only included in verbose mode
+func TestResetNetworkConfigVerboseFailure(t *testing.T) {
+	fake := useFakeRunner(t)
+	fake.On(CommandResult{ExitCode: 1, Stderr: []byte("access denied")}, "netsh", "winsock", "reset")
+
+	_, err := ResetNetworkConfig(context.Background(), Options{Out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "Partial success") || strings.Contains(err.Error(), "access denied") {
+		t.Errorf("error %v, want a partial success hint without the output", err)
+	}
+	_, err = ResetNetworkConfig(context.Background(), Options{Verbose: true, Out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("verbose error %v, want the command output", err)
+	}
+}
+
+func TestCleanTempFiles(t *testing.T) {
+	fake := useFakeRunner(t)
+	profile := t.TempDir()
+	temp := filepath.Join(profile, "AppData", "Local", "Temp")
+	t.Setenv("USERPROFILE", profile)
+	t.Setenv("TEMP", temp)
+
+	writeFiles(t, temp, map[string]string{
+		"a.tmp":      "12345",
+		"b.log":      "123",
+		"sub/c.tmp":  "1",
+		"sub/d.tmp":  "12",
+		"sub2/e.tmp": "1234",
+	})
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantRemoved int
+		wantFreed   int64
+		wantLeft    []string
+	}{
+		{name: "dry run", dryRun: true, wantRemoved: 2, wantFreed: 8,
+			wantLeft: []string{"a.tmp", "b.log", "sub/c.tmp", "sub/d.tmp", "sub2/e.tmp"}},
+		{name: "clean", wantRemoved: 2, wantFreed: 8,
+			wantLeft: []string{"sub/c.tmp", "sub/d.tmp", "sub2/e.tmp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, _ := LookupOperation("temp")
+			res, err := op.Execute(context.Background(), Options{DryRun: tt.dryRun, Out: io.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.ItemsRemoved != tt.wantRemoved || res.BytesFreed != tt.wantFreed {
+				t.Errorf("removed %d items (%d bytes), want %d (%d bytes)", res.ItemsRemoved, res.BytesFreed, tt.wantRemoved, tt.wantFreed)
+			}
+			if left := listFiles(t, temp); !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("left %q, want %q", left, tt.wantLeft)
+			}
+		})
+	}
+	if len(fake.Calls) != 0 {
+		t.Errorf("cleaning temp files ran %q", fake.Calls)
+	}
+}
+
+// writeFiles creates files with the given content under root, keyed by slash-separated
+// relative path, and backdates them so that they predate any clean
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	old := time.Now().Add(-48 * time.Hour)
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listFiles returns the slash-separated relative paths of the files under root, sorted
+func listFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
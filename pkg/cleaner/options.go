@@ -0,0 +1,28 @@
+package cleaner
+
+import (
+	"io"
+	"os"
+)
+
+// Options controls how cleaner operations execute
+type Options struct {
+	// Verbose echoes every command and file action to the console
+	Verbose bool
+	// DryRun reports what an operation would do without changing anything
+	DryRun bool
+	// Out receives progress and verbose output; nil means standard output
+	Out io.Writer
+	// Quarantine, when set, receives deleted files instead of removing them permanently
+	Quarantine *Quarantine
+	// Journal, when set, records the previous value of every setting an operation changes
+	Journal *ChangeJournal
+}
+
+// Writer returns the destination for progress output
+func (o Options) Writer() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
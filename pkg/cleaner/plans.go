@@ -0,0 +1,187 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// MaintenancePlan is a named batch of operations from the `plans:` config section, such
+// as "weekly" or "pre-imaging". Steps run in the order listed, except that a step is
+// moved after the steps it names in After.
+type MaintenancePlan struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description" json:"description,omitempty"`
+	Steps       []MaintenanceStep `yaml:"steps" json:"steps"`
+}
+
+// MaintenanceStep is one operation in a plan
+type MaintenanceStep struct {
+	// Operation is the ID of a registered operation, e.g. "sfc"
+	Operation string `yaml:"operation" json:"operation"`
+	// Timeout, when set, replaces the operation's default and configured timeouts
+	Timeout time.Duration `yaml:"timeout" json:"timeout_ns,omitempty"`
+	// ContinueOnError lets the plan go on when this step fails; by default it stops
+	ContinueOnError bool `yaml:"continue_on_error" json:"continue_on_error,omitempty"`
+	// After lists operations of the same plan that must run before this one
+	After []string `yaml:"after" json:"after,omitempty"`
+	// When, if set, runs the step only while its conditions hold
+	When *StepCondition `yaml:"when" json:"when,omitempty"`
+}
+
+// StepCondition restricts when a step runs. The two free space limits are alternatives:
+// the condition holds when free space is below either of the ones that are set.
+type StepCondition struct {
+	// FreeSpaceBelow runs the step only when the drive has less than this free
+	FreeSpaceBelow ByteSize `yaml:"free_space_below" json:"free_space_below,omitempty"`
+	// FreePercentBelow runs the step only when the drive has less than this percentage free
+	FreePercentBelow float64 `yaml:"free_percent_below" json:"free_percent_below,omitempty"`
+	// Drive is the drive the free space limits apply to; defaults to the system drive
+	Drive string `yaml:"drive" json:"drive,omitempty"`
+	// Admin runs the step only when wincleaner has administrator privileges, instead of
+	// reporting it as needing them
+	Admin bool `yaml:"admin" json:"admin,omitempty"`
+}
+
+// Validate checks that the plan's operations are registered, each appears once, and
+// its dependencies refer to steps of the plan without forming a cycle
+func (p MaintenancePlan) Validate() error {
+	if p.Name == "" {
+		return errors.New("plan is missing a name")
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("plan %q has no steps", p.Name)
+	}
+	seen := make(map[string]bool)
+	for _, step := range p.Steps {
+		if _, ok := LookupOperation(step.Operation); !ok {
+			return fmt.Errorf("plan %q: unknown operation %q", p.Name, step.Operation)
+		}
+		if seen[step.Operation] {
+			return fmt.Errorf("plan %q: operation %q is listed more than once", p.Name, step.Operation)
+		}
+		seen[step.Operation] = true
+		if c := step.When; c != nil && (c.FreePercentBelow < 0 || c.FreePercentBelow > 100) {
+			return fmt.Errorf("plan %q: step %q: free_percent_below must be between 0 and 100", p.Name, step.Operation)
+		}
+	}
+	for _, step := range p.Steps {
+		for _, dep := range step.After {
+			if dep == step.Operation {
+				return fmt.Errorf("plan %q: step %q cannot run after itself", p.Name, step.Operation)
+			}
+			if !seen[dep] {
+				return fmt.Errorf("plan %q: step %q runs after %q, which is not a step of the plan", p.Name, step.Operation, dep)
+			}
+		}
+	}
+	if _, err := p.Order(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Order returns the steps in execution order: each step after the steps it depends on,
+// and otherwise in the order listed
+func (p MaintenancePlan) Order() ([]MaintenanceStep, error) {
+	done := make(map[string]bool)
+	var ordered []MaintenanceStep
+	for len(ordered) < len(p.Steps) {
+		progressed := false
+		for _, step := range p.Steps {
+			if done[step.Operation] || !dependenciesDone(step, done) {
+				continue
+			}
+			ordered = append(ordered, step)
+			done[step.Operation] = true
+			progressed = true
+			// Start over so an earlier-listed step that just became ready runs next
+			break
+		}
+		if !progressed {
+			var waiting []string
+			for _, step := range p.Steps {
+				if !done[step.Operation] {
+					waiting = append(waiting, step.Operation)
+				}
+			}
+			return nil, fmt.Errorf("plan %q: the dependencies of %s form a cycle", p.Name, strings.Join(waiting, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+// dependenciesDone reports whether every step that step runs after is done
+func dependenciesDone(step MaintenanceStep, done map[string]bool) bool {
+	for _, dep := range step.After {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether the step may run now, and if not, why
+func (c *StepCondition) Check(ctx context.Context) (bool, string) {
+	if c == nil {
+		return true, ""
+	}
+	if c.Admin && !IsAdmin() {
+		return false, "requires administrator privileges"
+	}
+	if c.FreeSpaceBelow <= 0 && c.FreePercentBelow <= 0 {
+		return true, ""
+	}
+
+	drive := c.Drive
+	if drive == "" {
+		drive = SystemDrive()
+	}
+	volumes, err := SystemInfo.Volumes(ctx)
+	if err != nil {
+		return false, fmt.Sprintf("cannot read free space: %v", err)
+	}
+	for _, v := range volumes {
+		if !sameDrive(v.Drive, drive) {
+			continue
+		}
+		percent := 0.0
+		if v.Total > 0 {
+			percent = float64(v.Free) / float64(v.Total) * 100
+		}
+		if (c.FreeSpaceBelow > 0 && v.Free < uint64(c.FreeSpaceBelow)) || (c.FreePercentBelow > 0 && percent < c.FreePercentBelow) {
+			return true, ""
+		}
+		var limits []string
+		if c.FreeSpaceBelow > 0 {
+			limits = append(limits, c.FreeSpaceBelow.String())
+		}
+		if c.FreePercentBelow > 0 {
+			limits = append(limits, fmt.Sprintf("%g%%", c.FreePercentBelow))
+		}
+		return false, fmt.Sprintf("%s has %s (%.0f%%) free, not below %s", v.Drive, FormatBytes(float64(v.Free)), percent, strings.Join(limits, " or "))
+	}
+	return false, fmt.Sprintf("drive %s not found", drive)
+}
+
+// SystemDrive returns the drive Windows is installed on ("C:"), or "/" elsewhere
+func SystemDrive() string {
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		return drive
+	}
+	return "/"
+}
+
+// sameDrive compares drive names case-insensitively, ignoring a trailing separator
+func sameDrive(a, b string) bool {
+	trim := func(s string) string {
+		if len(s) > 1 {
+			s = strings.TrimRight(s, `\/`)
+		}
+		return s
+	}
+	return strings.EqualFold(trim(a), trim(b))
+}
@@ -0,0 +1,143 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// steps builds plan steps from operation IDs, each optionally followed by ">" and the
+// comma-separated operations it runs after, e.g. "sfc>dism"
+func steps(specs ...string) []MaintenanceStep {
+	var result []MaintenanceStep
+	for _, spec := range specs {
+		op, after, ok := strings.Cut(spec, ">")
+		step := MaintenanceStep{Operation: op}
+		if ok {
+			step.After = strings.Split(after, ",")
+		}
+		result = append(result, step)
+	}
+	return result
+}
+
+func TestMaintenancePlanOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []MaintenanceStep
+		want    []string
+		wantErr string
+	}{
+		{name: "listed order", steps: steps("temp", "disk", "recycle"), want: []string{"temp", "disk", "recycle"}},
+		{name: "a step moves after its dependency", steps: steps("sfc>dism", "dism"), want: []string{"dism", "sfc"}},
+		{name: "a chain", steps: steps("chkdsk>sfc", "sfc>dism", "dism"), want: []string{"dism", "sfc", "chkdsk"}},
+		{
+			name:  "a ready step keeps its place",
+			steps: steps("temp", "sfc>dism", "recycle", "dism", "disk"),
+			want:  []string{"temp", "recycle", "dism", "sfc", "disk"},
+		},
+		{name: "several dependencies", steps: steps("optimize>temp,recycle", "recycle", "temp"), want: []string{"recycle", "temp", "optimize"}},
+		{name: "a cycle", steps: steps("sfc>dism", "dism>sfc", "temp"), wantErr: "the dependencies of sfc, dism form a cycle"},
+		{name: "a longer cycle", steps: steps("temp", "sfc>chkdsk", "dism>sfc", "chkdsk>dism"), wantErr: "the dependencies of sfc, dism, chkdsk form a cycle"},
+		{name: "a step after a cycle", steps: steps("sfc>dism", "dism>sfc", "chkdsk>sfc"), wantErr: "the dependencies of sfc, dism, chkdsk form a cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := MaintenancePlan{Name: "test", Steps: tt.steps}
+			ordered, err := plan.Order()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, step := range ordered {
+				got = append(got, step.Operation)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenancePlanValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    MaintenancePlan
+		wantErr string
+	}{
+		{name: "valid", plan: MaintenancePlan{Name: "weekly", Steps: steps("temp", "sfc>dism", "dism")}},
+		{name: "no name", plan: MaintenancePlan{Steps: steps("temp")}, wantErr: "missing a name"},
+		{name: "no steps", plan: MaintenancePlan{Name: "empty"}, wantErr: "has no steps"},
+		{name: "unknown operation", plan: MaintenancePlan{Name: "p", Steps: steps("defragment")}, wantErr: `unknown operation "defragment"`},
+		{name: "duplicate operation", plan: MaintenancePlan{Name: "p", Steps: steps("temp", "disk", "temp")}, wantErr: `"temp" is listed more than once`},
+		{name: "after itself", plan: MaintenancePlan{Name: "p", Steps: steps("temp>temp")}, wantErr: "cannot run after itself"},
+		{name: "after a step not in the plan", plan: MaintenancePlan{Name: "p", Steps: steps("sfc>dism")}, wantErr: `runs after "dism", which is not a step of the plan`},
+		{name: "cycle", plan: MaintenancePlan{Name: "p", Steps: steps("sfc>dism", "dism>sfc")}, wantErr: "form a cycle"},
+		{
+			name:    "free percent out of range",
+			plan:    MaintenancePlan{Name: "p", Steps: []MaintenanceStep{{Operation: "temp", When: &StepCondition{FreePercentBelow: 150}}}},
+			wantErr: "free_percent_below must be between 0 and 100",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStepConditionCheck(t *testing.T) {
+	volumes := []VolumeInfo{
+		{Drive: `C:\`, Total: 100 * gb, Free: 8 * gb},
+		{Drive: "D:", Total: 1000 * gb, Free: 500 * gb},
+	}
+	tests := []struct {
+		name       string
+		condition  *StepCondition
+		err        error
+		wantRun    bool
+		wantReason string
+	}{
+		{name: "no condition", condition: nil, wantRun: true},
+		{name: "no limits", condition: &StepCondition{Drive: "C:"}, wantRun: true},
+		{name: "below the size limit", condition: &StepCondition{Drive: "C:", FreeSpaceBelow: 10 * gb}, wantRun: true},
+		{name: "below the percent limit", condition: &StepCondition{Drive: "c:", FreePercentBelow: 10}, wantRun: true},
+		{name: "below either limit", condition: &StepCondition{Drive: "C:", FreeSpaceBelow: gb, FreePercentBelow: 10}, wantRun: true},
+		{
+			name:       "above both limits",
+			condition:  &StepCondition{Drive: `D:\`, FreeSpaceBelow: 10 * gb, FreePercentBelow: 20},
+			wantReason: "D: has 500.0 GB (50%) free, not below 10.0 GB or 20%",
+		},
+		{name: "unknown drive", condition: &StepCondition{Drive: "E:", FreeSpaceBelow: gb}, wantReason: "drive E: not found"},
+		{name: "unreadable volumes", condition: &StepCondition{Drive: "C:", FreeSpaceBelow: gb}, err: errors.New("access denied"), wantReason: "cannot read free space: access denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := SystemInfo
+			t.Cleanup(func() { SystemInfo = saved })
+			SystemInfo = FakeSystemInfo{VolumeList: volumes, Err: tt.err}
+
+			run, reason := tt.condition.Check(context.Background())
+			if run != tt.wantRun || reason != tt.wantReason {
+				t.Errorf("Check() = %v, %q; want %v, %q", run, reason, tt.wantRun, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package cleaner
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CleanPolicy decides which files a directory clean removes.
+// The zero value removes every top-level file, matching the original behavior.
+type CleanPolicy struct {
+	// Recursive descends into subdirectories
+	Recursive bool `yaml:"recursive" json:"recursive"`
+	// MinAge only removes files not modified for at least this long
+	MinAge time.Duration `yaml:"min_age" json:"min_age"`
+	// Include, when non-empty, only removes files matching one of these glob patterns
+	Include []string `yaml:"include" json:"include,omitempty"`
+	// Exclude never removes matching files and never descends into matching directories
+	Exclude []string `yaml:"exclude" json:"exclude,omitempty"`
+	// MinSize only removes files at least this large
+	MinSize ByteSize `yaml:"min_size" json:"min_size"`
+	// MaxSize only removes files at most this large; zero means no limit
+	MaxSize ByteSize `yaml:"max_size" json:"max_size"`
+	// PruneEmptyDirs removes subdirectories left empty by the clean (requires Recursive)
+	PruneEmptyDirs bool `yaml:"prune_empty_dirs" json:"prune_empty_dirs"`
+}
+
+// TempPolicy is the policy CleanTempFiles applies; it is set from the temp_policy config section
+var TempPolicy CleanPolicy
+
+// Validate checks that every glob pattern in the policy is well formed
+func (p CleanPolicy) Validate() error {
+	for _, pattern := range append(append([]string{}, p.Include...), p.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if p.MaxSize > 0 && p.MinSize > p.MaxSize {
+		return fmt.Errorf("min_size %s exceeds max_size %s", p.MinSize, p.MaxSize)
+	}
+	return nil
+}
+
+// excluded reports whether rel, a slash-separated path relative to the cleaned root, matches an exclude pattern
+func (p CleanPolicy) excluded(rel string) bool {
+	return matchAny(p.Exclude, rel)
+}
+
+// selects reports whether a file is in scope for removal, and if not, why
+func (p CleanPolicy) selects(rel string, size int64, modTime, now time.Time) (bool, string) {
+	if len(p.Include) > 0 && !matchAny(p.Include, rel) {
+		return false, "not included"
+	}
+	if p.MinAge > 0 && now.Sub(modTime) < p.MinAge {
+		return false, "newer than min_age"
+	}
+	if size < int64(p.MinSize) {
+		return false, "smaller than min_size"
+	}
+	if p.MaxSize > 0 && size > int64(p.MaxSize) {
+		return false, "larger than max_size"
+	}
+	return true, ""
+}
+
+// matchAny matches rel against each pattern. Patterns containing a slash match the whole
+// relative path; others match just the final path element. Matching is case-insensitive,
+// like Windows file names.
+func matchAny(patterns []string, rel string) bool {
+	rel = strings.ToLower(filepath.ToSlash(rel))
+	base := path.Base(rel)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(filepath.ToSlash(pattern))
+		subject := base
+		if strings.Contains(pattern, "/") {
+			subject = rel
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ByteSize is a size in bytes that may be written in config files as a plain number
+// or with a unit suffix such as "500KB", "10MB" or "1GB" (powers of 1024)
+type ByteSize int64
+
+// String formats the size for display
+func (b ByteSize) String() string {
+	return FormatBytes(float64(b))
+}
+
+// UnmarshalYAML parses a plain byte count or a number with a unit suffix
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	size, err := ParseByteSize(raw)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// ParseByteSize parses strings such as "4096", "512KB", "10 MB" or "2GB"
+func ParseByteSize(raw string) (ByteSize, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	multipliers := []struct {
+		suffix string
+		factor int64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	factor := int64(1)
+	for _, m := range multipliers {
+		if strings.HasSuffix(s, m.suffix) {
+			factor = m.factor
+			s = strings.TrimSpace(strings.TrimSuffix(s, m.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+	return ByteSize(n * float64(factor)), nil
+}
@@ -0,0 +1,218 @@
+package cleaner
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// policyTree is the directory tree the policy tests clean
+var policyTree = map[string]string{
+	"a.tmp":          "12345",
+	"b.log":          "123",
+	"new.tmp":        "1234",
+	"keep/x.tmp":     "1",
+	"sub/c.tmp":      "1",
+	"sub/deep/d.tmp": "12",
+}
+
+// makePolicyTree builds policyTree under a temporary directory, with every file two days
+// old except new.tmp, which was modified an hour ago, and an empty directory sub/empty
+func makePolicyTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, policyTree)
+	recent := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "new.tmp"), recent, recent); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "sub", "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// listTree returns the slash-separated relative paths of everything under root, sorted,
+// with a trailing slash on directories
+func listTree(t *testing.T, root string) []string {
+	t.Helper()
+	var entries []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			rel += "/"
+		}
+		entries = append(entries, rel)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestCleanDirectoryPolicies(t *testing.T) {
+	allDirs := []string{"keep/", "sub/", "sub/deep/", "sub/empty/"}
+
+	tests := []struct {
+		name        string
+		policy      CleanPolicy
+		dryRun      bool
+		wantLeft    []string
+		wantRemoved int
+		wantFreed   int64
+	}{
+		{name: "zero value removes top-level files", policy: CleanPolicy{},
+			wantLeft:    []string{"keep/", "keep/x.tmp", "sub/", "sub/c.tmp", "sub/deep/", "sub/deep/d.tmp", "sub/empty/"},
+			wantRemoved: 3, wantFreed: 12},
+		{name: "recursive keeps directories", policy: CleanPolicy{Recursive: true},
+			wantLeft:    allDirs,
+			wantRemoved: 6, wantFreed: 16},
+		{name: "recursive prunes emptied directories", policy: CleanPolicy{Recursive: true, PruneEmptyDirs: true},
+			wantLeft:    nil,
+			wantRemoved: 10, wantFreed: 16},
+		{name: "prune keeps directories with remaining files", policy: CleanPolicy{Recursive: true, PruneEmptyDirs: true, Include: []string{"d.tmp"}},
+			wantLeft:    []string{"a.tmp", "b.log", "keep/", "keep/x.tmp", "new.tmp", "sub/", "sub/c.tmp"},
+			wantRemoved: 3, wantFreed: 2},
+		{name: "min age keeps recent files", policy: CleanPolicy{MinAge: 24 * time.Hour},
+			wantLeft:    []string{"keep/", "keep/x.tmp", "new.tmp", "sub/", "sub/c.tmp", "sub/deep/", "sub/deep/d.tmp", "sub/empty/"},
+			wantRemoved: 2, wantFreed: 8},
+		{name: "include matches case-insensitively", policy: CleanPolicy{Recursive: true, Include: []string{"*.TMP"}},
+			wantLeft:    append([]string{"b.log"}, allDirs...),
+			wantRemoved: 5, wantFreed: 13},
+		{name: "exclude skips whole directories", policy: CleanPolicy{Recursive: true, PruneEmptyDirs: true, Exclude: []string{"keep", "*.log"}},
+			wantLeft:    []string{"b.log", "keep/", "keep/x.tmp"},
+			wantRemoved: 7, wantFreed: 12},
+		{name: "exclude patterns with a slash match the relative path", policy: CleanPolicy{Recursive: true, Exclude: []string{"sub/deep/*"}},
+			wantLeft:    []string{"keep/", "sub/", "sub/deep/", "sub/deep/d.tmp", "sub/empty/"},
+			wantRemoved: 5, wantFreed: 14},
+		{name: "min size", policy: CleanPolicy{Recursive: true, MinSize: 3},
+			wantLeft:    []string{"keep/", "keep/x.tmp", "sub/", "sub/c.tmp", "sub/deep/", "sub/deep/d.tmp", "sub/empty/"},
+			wantRemoved: 3, wantFreed: 12},
+		{name: "max size", policy: CleanPolicy{Recursive: true, MaxSize: 3},
+			wantLeft:    []string{"a.tmp", "keep/", "new.tmp", "sub/", "sub/deep/", "sub/empty/"},
+			wantRemoved: 4, wantFreed: 7},
+		{name: "dry run changes nothing", policy: CleanPolicy{Recursive: true, PruneEmptyDirs: true}, dryRun: true,
+			wantLeft:    []string{"a.tmp", "b.log", "keep/", "keep/x.tmp", "new.tmp", "sub/", "sub/c.tmp", "sub/deep/", "sub/deep/d.tmp", "sub/empty/"},
+			wantRemoved: 10, wantFreed: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makePolicyTree(t)
+			opts := Options{DryRun: tt.dryRun, Out: io.Discard}
+			res := newResult(opts)
+
+			if err := cleanDirectory(context.Background(), root, tt.policy, opts, res); err != nil {
+				t.Fatal(err)
+			}
+			if left := listTree(t, root); !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("left %q, want %q", left, tt.wantLeft)
+			}
+			if res.ItemsRemoved != tt.wantRemoved || res.BytesFreed != tt.wantFreed {
+				t.Errorf("removed %d items (%d bytes), want %d (%d bytes)", res.ItemsRemoved, res.BytesFreed, tt.wantRemoved, tt.wantFreed)
+			}
+			if len(res.ItemsSkipped) != 0 {
+				t.Errorf("skipped %+v", res.ItemsSkipped)
+			}
+		})
+	}
+}
+
+// Files written after the scan started are left alone
+func TestCleanDirectorySkipsFilesModifiedDuringScan(t *testing.T) {
+	root := makePolicyTree(t)
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "a.tmp"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Out: io.Discard}
+	res := newResult(opts)
+
+	if err := cleanDirectory(context.Background(), root, CleanPolicy{}, opts, res); err != nil {
+		t.Fatal(err)
+	}
+	want := []SkippedItem{{Path: filepath.Join(root, "a.tmp"), Reason: "modified during scan"}}
+	if !reflect.DeepEqual(res.ItemsSkipped, want) {
+		t.Errorf("skipped %+v, want %+v", res.ItemsSkipped, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.tmp")); err != nil {
+		t.Errorf("a.tmp was removed: %v", err)
+	}
+}
+
+func TestCleanDirectoryStopsWhenCanceled(t *testing.T) {
+	root := makePolicyTree(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := Options{Out: io.Discard}
+
+	if err := cleanDirectory(ctx, root, CleanPolicy{Recursive: true}, opts, newResult(opts)); err != context.Canceled {
+		t.Errorf("error %v, want %v", err, context.Canceled)
+	}
+	if left := listTree(t, root); len(left) != 10 {
+		t.Errorf("a canceled clean removed files: %q", left)
+	}
+}
+
+func TestCleanPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CleanPolicy
+		wantErr string
+	}{
+		{name: "zero value", policy: CleanPolicy{}},
+		{name: "valid patterns", policy: CleanPolicy{Include: []string{"*.tmp", "cache/*"}, Exclude: []string{"keep?"}}},
+		{name: "bad include", policy: CleanPolicy{Include: []string{"[a-"}}, wantErr: `invalid pattern "[a-"`},
+		{name: "bad exclude", policy: CleanPolicy{Exclude: []string{"a\\"}}, wantErr: `invalid pattern "a\\"`},
+		{name: "min size above max size", policy: CleanPolicy{MinSize: 2048, MaxSize: 1024}, wantErr: "exceeds max_size"},
+		{name: "min size without max size", policy: CleanPolicy{MinSize: 2048}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteSize
+		wantErr bool
+	}{
+		{in: "4096", want: 4096},
+		{in: "512KB", want: 512 << 10},
+		{in: "10 mb", want: 10 << 20},
+		{in: "1.5GB", want: 3 << 29},
+		{in: "2TB", want: 2 << 40},
+		{in: "7B", want: 7},
+		{in: "", wantErr: true},
+		{in: "-1KB", wantErr: true},
+		{in: "lots", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d, error: %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
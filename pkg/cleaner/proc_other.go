@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killTreeOnCancel runs the command in its own process group so that cancellation
+// terminates the command and every process it spawned
+func killTreeOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package cleaner
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// killTreeOnCancel makes cancellation terminate the command and every process it spawned;
+// tools such as DISM and defrag hand their work to child processes
+func killTreeOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
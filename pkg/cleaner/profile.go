@@ -0,0 +1,242 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tweak is one optimal Windows setting, expressed as the registry value it controls
+// and the values that turn it on and off
+type Tweak struct {
+	// ID names the tweak in profiles, e.g. "visual_effects"
+	ID string
+	// Name is the display name
+	Name string
+	// Description explains what enabling the tweak does
+	Description string
+	// Path and Value identify the registry value, e.g. HKCU:\...\Personalize, EnableTransparency
+	Path  string
+	Value string
+	// Enabled is written when the tweak is enabled
+	Enabled *RegistryValue
+	// Disabled is written when the tweak is disabled; nil deletes the value
+	Disabled *RegistryValue
+	// EnabledMessage and DisabledMessage are printed after a successful change
+	EnabledMessage  string
+	DisabledMessage string
+}
+
+// Tweaks lists the settings managed by optimal, in the order they are applied
+var Tweaks = []Tweak{
+	{
+		ID:              "fast_boot",
+		Name:            "Disable Fast Boot",
+		Description:     "Turns off Fast Startup, which can cause update and dual-boot problems.",
+		Path:            `HKLM:\SYSTEM\CurrentControlSet\Control\Session Manager\Power`,
+		Value:           "HiberbootEnabled",
+		Enabled:         DWORDValue(0),
+		Disabled:        DWORDValue(1),
+		EnabledMessage:  "Fast Boot disabled.",
+		DisabledMessage: "Fast Boot enabled.",
+	},
+	{
+		ID:              "visual_effects",
+		Name:            "Adjust Visual Effects for Best Performance",
+		Description:     "Disables most Windows animations and effects to improve speed.",
+		Path:            `HKCU:\Software\Microsoft\Windows\CurrentVersion\Explorer\VisualEffects`,
+		Value:           "VisualFXSetting",
+		Enabled:         DWORDValue(2),
+		Disabled:        DWORDValue(0),
+		EnabledMessage:  "Visual effects set for best performance.",
+		DisabledMessage: "Visual effects reverted to default.",
+	},
+	{
+		ID:              "transparency",
+		Name:            "Disable Transparency Effects",
+		Description:     "Turns off window transparency to reduce GPU usage.",
+		Path:            `HKCU:\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`,
+		Value:           "EnableTransparency",
+		Enabled:         DWORDValue(0),
+		Disabled:        DWORDValue(1),
+		EnabledMessage:  "Transparency effects disabled.",
+		DisabledMessage: "Transparency effects enabled.",
+	},
+	{
+		ID:              "storage_sense",
+		Name:            "Enable Storage Sense",
+		Description:     "Automatically frees up disk space by deleting unnecessary files.",
+		Path:            `HKCU:\Software\Microsoft\Windows\CurrentVersion\StorageSense\Parameters\StoragePolicy`,
+		Value:           "01",
+		Enabled:         DWORDValue(1),
+		Disabled:        DWORDValue(0),
+		EnabledMessage:  "Storage Sense enabled.",
+		DisabledMessage: "Storage Sense disabled.",
+	},
+	{
+		ID:              "startup_delay",
+		Name:            "Disable Startup Delay",
+		Description:     "Speeds up startup for apps in the Startup folder.",
+		Path:            `HKCU:\Software\Microsoft\Windows\CurrentVersion\Explorer\Serialize`,
+		Value:           "StartupDelayInMSec",
+		Enabled:         DWORDValue(0),
+		Disabled:        nil,
+		EnabledMessage:  "Startup delay disabled.",
+		DisabledMessage: "Startup delay restored to default.",
+	},
+}
+
+// LookupTweak returns the tweak with the given ID
+func LookupTweak(id string) (Tweak, bool) {
+	for _, t := range Tweaks {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Tweak{}, false
+}
+
+// Tweak states in a profile
+const (
+	TweakEnable  = "enable"
+	TweakDisable = "disable"
+	TweakSkip    = "skip"
+)
+
+// Power plans accepted in a profile, mapped to their built-in scheme GUIDs
+var powerPlans = map[string]string{
+	"high_performance": "8c5e7fda-e8bf-4a96-9a85-a6e23a8c635c",
+	"balanced":         "381b4222-f694-41f0-9685-ff5bb260df2e",
+	"power_saver":      "a1841308-3541-4fab-bc81-f71556f20b4a",
+}
+
+// OptimalProfile is a declarative set of optimal settings that can be applied unattended
+type OptimalProfile struct {
+	// PowerPlan is high_performance, balanced, power_saver or a scheme GUID; empty leaves it unchanged
+	PowerPlan string `yaml:"power_plan" json:"power_plan,omitempty"`
+	// Tweaks maps tweak IDs to enable, disable or skip; unlisted tweaks are skipped
+	Tweaks map[string]string `yaml:"tweaks" json:"tweaks,omitempty"`
+}
+
+// RecommendedProfile is the built-in profile selected with --profile recommended
+var RecommendedProfile = OptimalProfile{
+	PowerPlan: "balanced",
+	Tweaks: map[string]string{
+		"fast_boot":      TweakEnable,
+		"visual_effects": TweakEnable,
+		"transparency":   TweakEnable,
+		"storage_sense":  TweakEnable,
+		"startup_delay":  TweakEnable,
+	},
+}
+
+// LoadOptimalProfile reads a profile from a YAML file; the name "recommended" selects RecommendedProfile
+func LoadOptimalProfile(path string) (OptimalProfile, error) {
+	if path == "recommended" {
+		return RecommendedProfile, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return OptimalProfile{}, fmt.Errorf("reading profile: %w", err)
+	}
+	var profile OptimalProfile
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return OptimalProfile{}, fmt.Errorf("parsing profile %s: %w", path, err)
+	}
+	return profile, profile.Validate()
+}
+
+// Validate checks that every tweak and state in the profile is known
+func (p OptimalProfile) Validate() error {
+	if p.PowerPlan != "" {
+		if _, err := p.powerScheme(); err != nil {
+			return err
+		}
+	}
+	var ids []string
+	for id := range p.Tweaks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, ok := LookupTweak(id); !ok {
+			return fmt.Errorf("unknown tweak %q", id)
+		}
+		switch p.Tweaks[id] {
+		case TweakEnable, TweakDisable, TweakSkip:
+		default:
+			return fmt.Errorf("tweak %q: state must be enable, disable or skip, not %q", id, p.Tweaks[id])
+		}
+	}
+	return nil
+}
+
+// powerScheme returns the scheme GUID for the profile's power plan
+func (p OptimalProfile) powerScheme() (string, error) {
+	if scheme, ok := powerPlans[p.PowerPlan]; ok {
+		return scheme, nil
+	}
+	if schemeGUID.MatchString(p.PowerPlan) {
+		return strings.ToLower(p.PowerPlan), nil
+	}
+	return "", fmt.Errorf("unknown power plan %q", p.PowerPlan)
+}
+
+// ApplyOptimalProfile applies a profile without prompting. Every tweak is attempted; the
+// error lists those that failed.
+func ApplyOptimalProfile(ctx context.Context, opts Options, profile OptimalProfile) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	if err := profile.Validate(); err != nil {
+		return res, err
+	}
+
+	var failures []string
+	if profile.PowerPlan != "" {
+		scheme, _ := profile.powerScheme()
+		if err := changePowerScheme(ctx, opts, res, scheme); err != nil {
+			fmt.Fprintf(opts.Writer(), "Failed to set power plan: %v\n", err)
+			failures = append(failures, "power plan")
+		} else {
+			fmt.Fprintln(opts.Writer(), "Power plan applied successfully.")
+		}
+	}
+
+	for _, tweak := range Tweaks {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		state := profile.Tweaks[tweak.ID]
+		if state == "" || state == TweakSkip {
+			continue
+		}
+		if err := applyTweak(ctx, opts, res, tweak, state == TweakEnable); err != nil {
+			fmt.Fprintf(opts.Writer(), "Failed to apply %s: %v\n", tweak.ID, err)
+			failures = append(failures, tweak.ID)
+		}
+	}
+
+	if len(failures) > 0 {
+		return res, errors.New("failed to apply: " + strings.Join(failures, ", "))
+	}
+	return res, nil
+}
+
+// applyTweak writes a tweak's enabled or disabled value
+func applyTweak(ctx context.Context, opts Options, res *OperationResult, tweak Tweak, enable bool) error {
+	value, message := tweak.Disabled, tweak.DisabledMessage
+	if enable {
+		value, message = tweak.Enabled, tweak.EnabledMessage
+	}
+	if err := changeRegistryValue(ctx, opts, res, tweak.Path, tweak.Value, value); err != nil {
+		return err
+	}
+	fmt.Fprintln(opts.Writer(), message)
+	return nil
+}
@@ -0,0 +1,402 @@
+package cleaner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunIDLayout is the time layout of run IDs, which name quarantine and journal entries
+const RunIDLayout = "20060102-150405"
+
+// NewRunID returns the run ID for a run started at t
+func NewRunID(t time.Time) string {
+	return t.Format(RunIDLayout)
+}
+
+// RunTime recovers the start time encoded in a run ID, or the zero time if it has none
+func RunTime(runID string) time.Time {
+	t, err := time.ParseInLocation(RunIDLayout, runID, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// ValidateRunID rejects run IDs that could name anything but an entry directly inside a
+// store, such as "..", "../other" or an absolute path. Run IDs come from the command line.
+func ValidateRunID(runID string) error {
+	if runID == "" || runID == "." || runID == ".." || strings.ContainsAny(runID, `/\:`) || filepath.Base(runID) != runID {
+		return fmt.Errorf("invalid run ID %q", runID)
+	}
+	return nil
+}
+
+// manifestFile is the append-only JSON lines manifest kept in each quarantine run directory
+const manifestFile = "manifest.jsonl"
+
+// QuarantineEntry describes one file moved into quarantine
+type QuarantineEntry struct {
+	OriginalPath string      `json:"original_path" yaml:"original_path"`
+	StoredPath   string      `json:"stored_path" yaml:"stored_path"`
+	Size         int64       `json:"size" yaml:"size"`
+	Mode         fs.FileMode `json:"mode" yaml:"mode"`
+	ModTime      time.Time   `json:"mod_time" yaml:"mod_time"`
+	SHA256       string      `json:"sha256" yaml:"sha256"`
+}
+
+// QuarantineRun summarizes the files quarantined by one wincleaner run
+type QuarantineRun struct {
+	RunID   string            `json:"run_id" yaml:"run_id"`
+	Created time.Time         `json:"created" yaml:"created"`
+	Size    int64             `json:"size" yaml:"size"`
+	Entries []QuarantineEntry `json:"entries" yaml:"entries"`
+	// Error is set when the run's manifest is missing or corrupt. Entries then lists at
+	// most the readable part, and Size is that of all the files stored for the run.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Quarantine moves deleted files into a dated store instead of removing them,
+// so that they can be restored with RestoreQuarantine
+type Quarantine struct {
+	mu     sync.Mutex
+	dir    string
+	count  int
+	stored int
+}
+
+// NewQuarantine returns the quarantine store for a run under root; nothing is
+// created on disk until the first file is stored
+func NewQuarantine(root, runID string) *Quarantine {
+	return &Quarantine{dir: filepath.Join(root, runID)}
+}
+
+// Store moves the file at path into quarantine and appends it to the manifest
+func (q *Quarantine) Store(path string, info fs.FileInfo) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	filesDir := filepath.Join(q.dir, "files")
+	if err := os.MkdirAll(filesDir, 0o700); err != nil {
+		return fmt.Errorf("creating quarantine store: %w", err)
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Several runs may share a run ID, so never reuse a stored name
+	var stored string
+	for {
+		q.count++
+		stored = filepath.Join("files", strconv.Itoa(q.count)+"_"+filepath.Base(path))
+		if _, err := os.Lstat(filepath.Join(q.dir, stored)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if err := moveFile(path, filepath.Join(q.dir, stored)); err != nil {
+		return err
+	}
+
+	entry := QuarantineEntry{
+		OriginalPath: path,
+		StoredPath:   stored,
+		Size:         info.Size(),
+		Mode:         info.Mode().Perm(),
+		ModTime:      info.ModTime(),
+		SHA256:       hash,
+	}
+	// A file without a manifest entry could never be restored, so put it back
+	if err := appendManifest(q.dir, entry); err != nil {
+		if restoreErr := moveFile(filepath.Join(q.dir, stored), path); restoreErr != nil {
+			return fmt.Errorf("recording %s in the quarantine manifest: %w (the file remains at %s: %v)", path, err, filepath.Join(q.dir, stored), restoreErr)
+		}
+		return fmt.Errorf("recording %s in the quarantine manifest: %w", path, err)
+	}
+	q.stored++
+	return nil
+}
+
+// Stored returns how many files this store has quarantined
+func (q *Quarantine) Stored() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stored
+}
+
+// ListQuarantine returns the quarantined runs under root, oldest first. Runs whose
+// manifest cannot be read are listed with their Error set rather than left out, so
+// that they can still be purged.
+func ListQuarantine(root string) ([]QuarantineRun, error) {
+	dirs, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []QuarantineRun
+	for _, dir := range dirs {
+		if !dir.IsDir() || ValidateRunID(dir.Name()) != nil {
+			continue
+		}
+		run, err := readQuarantineRun(root, dir.Name())
+		if err != nil {
+			run.Error = err.Error()
+			run.Size = dirSize(filepath.Join(root, dir.Name(), "files"))
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Created.Before(runs[j].Created) })
+	return runs, nil
+}
+
+// RestoreQuarantine moves the files quarantined by runID back to their original paths.
+// Files whose original path is occupied, or whose content no longer matches the
+// recorded hash, are left in quarantine and reported as skipped.
+func RestoreQuarantine(ctx context.Context, opts Options, root, runID string) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	run, err := readQuarantineRun(root, runID)
+	if err != nil {
+		return res, err
+	}
+
+	runDir := filepath.Join(root, runID)
+	var remaining []QuarantineEntry
+	for _, entry := range run.Entries {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if reason := restoreEntry(runDir, entry, opts); reason != "" {
+			res.skip(entry.OriginalPath, reason)
+			remaining = append(remaining, entry)
+			continue
+		}
+		res.restored(entry.Size)
+	}
+
+	if opts.DryRun {
+		return res, nil
+	}
+	if len(remaining) == 0 {
+		return res, os.RemoveAll(runDir)
+	}
+	return res, rewriteManifest(runDir, remaining)
+}
+
+// PurgeQuarantine permanently deletes the directories of quarantined runs older than
+// maxAge, including runs whose manifest cannot be read. Those that are not yet old
+// enough are reported as skipped, so that the damage does not go unnoticed.
+func PurgeQuarantine(ctx context.Context, opts Options, root string, maxAge time.Duration) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	runs, err := ListQuarantine(root)
+	if err != nil {
+		return res, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, run := range runs {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		runDir := filepath.Join(root, run.RunID)
+		if run.Created.After(cutoff) {
+			if run.Error != "" {
+				res.skip(runDir, "unreadable quarantine run, kept until it is old enough to purge: "+run.Error)
+			}
+			continue
+		}
+		contents := fmt.Sprintf("%d files", len(run.Entries))
+		if run.Error != "" {
+			contents = "unreadable manifest"
+		}
+		if opts.DryRun {
+			fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would purge quarantine run %s (%s, %s)\n", run.RunID, contents, FormatBytes(float64(run.Size)))
+			res.removed(run.Size)
+			continue
+		}
+		if opts.Verbose {
+			fmt.Fprintf(opts.Writer(), "[VERBOSE] Purging quarantine run %s\n", run.RunID)
+		}
+		if err := os.RemoveAll(runDir); err != nil {
+			res.skip(runDir, removalSkipReason(err))
+			continue
+		}
+		res.removed(run.Size)
+	}
+	return res, nil
+}
+
+// restoreEntry moves one file back and returns a skip reason, or "" on success
+func restoreEntry(runDir string, entry QuarantineEntry, opts Options) string {
+	stored := filepath.Join(runDir, entry.StoredPath)
+	if _, err := os.Lstat(entry.OriginalPath); err == nil {
+		return "original path exists"
+	}
+	hash, err := hashFile(stored)
+	if err != nil {
+		return fmt.Sprintf("quarantined copy unreadable: %v", err)
+	}
+	if hash != entry.SHA256 {
+		return "quarantined copy does not match recorded hash"
+	}
+
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would restore: %s\n", entry.OriginalPath)
+		return ""
+	}
+	if opts.Verbose {
+		fmt.Fprintf(opts.Writer(), "[VERBOSE] Restoring file: %s\n", entry.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.OriginalPath), 0o755); err != nil {
+		return err.Error()
+	}
+	if err := moveFile(stored, entry.OriginalPath); err != nil {
+		return err.Error()
+	}
+	os.Chmod(entry.OriginalPath, entry.Mode)
+	os.Chtimes(entry.OriginalPath, entry.ModTime, entry.ModTime)
+	return ""
+}
+
+// readQuarantineRun loads the manifest of one run. On a missing or corrupt manifest
+// it still returns the run's ID and creation time, with the entries read so far.
+func readQuarantineRun(root, runID string) (QuarantineRun, error) {
+	if err := ValidateRunID(runID); err != nil {
+		return QuarantineRun{}, err
+	}
+	runDir := filepath.Join(root, runID)
+	// The run ID encodes the start time; fall back to the directory's for any other name
+	run := QuarantineRun{RunID: runID, Created: RunTime(runID)}
+	if run.Created.IsZero() {
+		if info, err := os.Stat(runDir); err == nil {
+			run.Created = info.ModTime()
+		}
+	}
+	f, err := os.Open(filepath.Join(runDir, manifestFile))
+	if err != nil {
+		return run, fmt.Errorf("quarantine run %q not found: %w", runID, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry QuarantineEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return run, fmt.Errorf("corrupt manifest in quarantine run %q: %w", runID, err)
+		}
+		run.Entries = append(run.Entries, entry)
+		run.Size += entry.Size
+	}
+	return run, scanner.Err()
+}
+
+// dirSize totals the files under dir, ignoring those that cannot be read
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// appendManifest adds one entry to a run's manifest
+func appendManifest(runDir string, entry QuarantineEntry) error {
+	f, err := os.OpenFile(filepath.Join(runDir, manifestFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(entry)
+}
+
+// rewriteManifest replaces a run's manifest with the given entries
+func rewriteManifest(runDir string, entries []QuarantineEntry) error {
+	tmp := filepath.Join(runDir, manifestFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(runDir, manifestFile))
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// moveFile renames src to dst, falling back to copy-and-delete across volumes.
+// If the source cannot be deleted (e.g. it is locked) the copy is discarded.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	if err := os.Remove(src); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cleaner
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quarantineFiles writes files under root and moves them into a quarantine run
+func quarantineFiles(t *testing.T, store, root, runID string, files map[string]string) {
+	t.Helper()
+	writeFiles(t, root, files)
+	q := NewQuarantine(store, runID)
+	for rel := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Store(path, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// A restore puts space back, so it is counted apart from freed space
+func TestRestoreQuarantine(t *testing.T) {
+	tests := []struct {
+		name     string
+		dryRun   bool
+		wantLeft []string
+	}{
+		{name: "restore", wantLeft: []string{"a.tmp", "sub/b.tmp"}},
+		{name: "dry run", dryRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, root := t.TempDir(), t.TempDir()
+			quarantineFiles(t, store, root, testRunID, map[string]string{"a.tmp": "12345", "sub/b.tmp": "123"})
+
+			res, err := RestoreQuarantine(context.Background(), Options{DryRun: tt.dryRun, Out: io.Discard}, store, testRunID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.ItemsRestored != 2 || res.BytesRestored != 8 {
+				t.Errorf("restored %d items (%d bytes), want 2 (8 bytes)", res.ItemsRestored, res.BytesRestored)
+			}
+			if res.ItemsRemoved != 0 || res.BytesFreed != 0 {
+				t.Errorf("restore counted %d items (%d bytes) as removed", res.ItemsRemoved, res.BytesFreed)
+			}
+			if left := listFiles(t, root); !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("left %q, want %q", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+// Purge works by run directory, so runs with a missing or corrupt manifest still age out
+func TestPurgeQuarantine(t *testing.T) {
+	store, root := t.TempDir(), t.TempDir()
+	old, recent := NewRunID(time.Now().Add(-60*day)), NewRunID(time.Now().Add(-day))
+	quarantineFiles(t, store, root, old, map[string]string{"a.tmp": "12345"})
+	quarantineFiles(t, store, root, recent, map[string]string{"b.tmp": "123"})
+
+	// corrupt has a damaged manifest; missing has none, and its age comes from its mtime
+	corrupt := NewRunID(time.Now().Add(-40 * day))
+	quarantineFiles(t, store, root, corrupt, map[string]string{"c.tmp": "1234567"})
+	if err := os.WriteFile(filepath.Join(store, corrupt, manifestFile), []byte(`{"original_path":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Join(store, "missing"), map[string]string{"files/1_d.tmp": "12"})
+	past := time.Now().Add(-90 * day)
+	if err := os.Chtimes(filepath.Join(store, "missing"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	young := NewRunID(time.Now())
+	writeFiles(t, filepath.Join(store, young), map[string]string{"files/1_e.tmp": "1"})
+
+	runs, err := ListQuarantine(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var unreadable []string
+	for _, run := range runs {
+		if run.Error != "" {
+			unreadable = append(unreadable, run.RunID)
+		}
+	}
+	if want := []string{"missing", corrupt, young}; !reflect.DeepEqual(unreadable, want) {
+		t.Errorf("listed %q as unreadable, want %q", unreadable, want)
+	}
+
+	res, err := PurgeQuarantine(context.Background(), Options{Out: io.Discard}, store, 30*day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ItemsRemoved != 3 || res.BytesFreed != 5+7+2 {
+		t.Errorf("purged %d runs (%d bytes), want 3 (14 bytes)", res.ItemsRemoved, res.BytesFreed)
+	}
+	if len(res.ItemsSkipped) != 1 || !strings.Contains(res.ItemsSkipped[0].Path, young) {
+		t.Errorf("skipped %+v, want the young unreadable run", res.ItemsSkipped)
+	}
+	left, err := os.ReadDir(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, dir := range left {
+		names = append(names, dir.Name())
+	}
+	if want := []string{recent, young}; !reflect.DeepEqual(names, want) {
+		t.Errorf("kept %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,104 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RegistryReader reads registry values. Paths use PowerShell drive syntax, e.g.
+// HKCU:\Software\Microsoft\Windows\CurrentVersion\Themes\Personalize; the long forms
+// HKEY_CURRENT_USER\... and HKCU\... are also accepted.
+type RegistryReader interface {
+	// GetValue returns the named value, or nil if it or its key does not exist
+	GetValue(ctx context.Context, path, name string) (*RegistryValue, error)
+	// KeyExists reports whether the key exists
+	KeyExists(ctx context.Context, path string) (bool, error)
+}
+
+// Registry reads and writes DWORD and string registry values
+type Registry interface {
+	RegistryReader
+	// SetValue writes the named value, creating its key if needed
+	SetValue(ctx context.Context, path, name string, value *RegistryValue) error
+	// DeleteValue removes the named value; a value or key that does not exist is not an error
+	DeleteValue(ctx context.Context, path, name string) error
+	// EnsureKey creates the key and any missing parents
+	EnsureKey(ctx context.Context, path string) error
+	// DeleteKey removes a key that holds no values or subkeys. A key that does not exist
+	// is not an error; one that is not empty is left in place and ErrKeyNotEmpty returned.
+	DeleteKey(ctx context.Context, path string) error
+}
+
+// WinRegistry is the registry used by optimal settings, audits and rollback; it is the
+// native Windows registry, and tests replace it with a FakeRegistry
+var WinRegistry Registry = NativeRegistry{}
+
+// ErrRegistryUnsupported is returned by NativeRegistry on platforms without a registry
+var ErrRegistryUnsupported = errors.New("the Windows registry is not available on this platform")
+
+// ErrKeyNotEmpty is returned by DeleteKey for a key that still holds values or subkeys
+var ErrKeyNotEmpty = errors.New("registry key is not empty")
+
+// Registry roots accepted in paths, keyed by their short and long names
+var registryRoots = map[string]string{
+	"HKCR": "HKCR", "HKEY_CLASSES_ROOT": "HKCR",
+	"HKCU": "HKCU", "HKEY_CURRENT_USER": "HKCU",
+	"HKLM": "HKLM", "HKEY_LOCAL_MACHINE": "HKLM",
+	"HKU": "HKU", "HKEY_USERS": "HKU",
+	"HKCC": "HKCC", "HKEY_CURRENT_CONFIG": "HKCC",
+}
+
+// splitRegistryPath splits a registry path into its short root name (e.g. "HKCU") and subkey
+func splitRegistryPath(path string) (root, subkey string, err error) {
+	root, subkey, _ = strings.Cut(path, `\`)
+	short, ok := registryRoots[strings.ToUpper(strings.TrimSuffix(root, ":"))]
+	if !ok {
+		return "", "", fmt.Errorf("invalid registry path %q: unknown root %q", path, root)
+	}
+	return short, strings.Trim(subkey, `\`), nil
+}
+
+// RegistryValue kinds
+const (
+	RegistryDWORD  = "dword"
+	RegistryString = "string"
+)
+
+// RegistryValue is a typed registry value
+type RegistryValue struct {
+	Kind   string `json:"kind" yaml:"kind"` // RegistryDWORD or RegistryString
+	DWORD  uint32 `json:"dword,omitempty" yaml:"dword,omitempty"`
+	String string `json:"string,omitempty" yaml:"string,omitempty"`
+}
+
+// DWORDValue returns a DWORD registry value
+func DWORDValue(v uint32) *RegistryValue {
+	return &RegistryValue{Kind: RegistryDWORD, DWORD: v}
+}
+
+// StringValue returns a string registry value
+func StringValue(v string) *RegistryValue {
+	return &RegistryValue{Kind: RegistryString, String: v}
+}
+
+// Equal reports whether two registry values are identical; two nil values are equal
+func (v *RegistryValue) Equal(other *RegistryValue) bool {
+	if v == nil || other == nil {
+		return v == other
+	}
+	return *v == *other
+}
+
+// Display formats the value for reports
+func (v *RegistryValue) Display() string {
+	if v == nil {
+		return "not set"
+	}
+	if v.Kind == RegistryDWORD {
+		return strconv.FormatUint(uint64(v.DWORD), 10)
+	}
+	return strconv.Quote(v.String)
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package cleaner
+
+import "context"
+
+// NativeRegistry stands in for the Windows registry on other platforms; every call
+// fails with ErrRegistryUnsupported
+type NativeRegistry struct{}
+
+// GetValue implements Registry
+func (NativeRegistry) GetValue(ctx context.Context, path, name string) (*RegistryValue, error) {
+	return nil, ErrRegistryUnsupported
+}
+
+// SetValue implements Registry
+func (NativeRegistry) SetValue(ctx context.Context, path, name string, value *RegistryValue) error {
+	return ErrRegistryUnsupported
+}
+
+// DeleteValue implements Registry
+func (NativeRegistry) DeleteValue(ctx context.Context, path, name string) error {
+	return ErrRegistryUnsupported
+}
+
+// EnsureKey implements Registry
+func (NativeRegistry) EnsureKey(ctx context.Context, path string) error {
+	return ErrRegistryUnsupported
+}
+
+// KeyExists implements Registry
+func (NativeRegistry) KeyExists(ctx context.Context, path string) (bool, error) {
+	return false, ErrRegistryUnsupported
+}
+
+// DeleteKey implements Registry
+func (NativeRegistry) DeleteKey(ctx context.Context, path string) error {
+	return ErrRegistryUnsupported
+}
@@ -0,0 +1,179 @@
+//go:build windows
+
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// NativeRegistry accesses the Windows registry directly through the Win32 API.
+// Keys are opened in the 64-bit view so that 32-bit builds see the same values as Explorer.
+type NativeRegistry struct{}
+
+// registry.DeleteKey cannot select the 64-bit view
+var procRegDeleteKeyExW = windows.NewLazySystemDLL("advapi32.dll").NewProc("RegDeleteKeyExW")
+
+var nativeRoots = map[string]registry.Key{
+	"HKCR": registry.CLASSES_ROOT,
+	"HKCU": registry.CURRENT_USER,
+	"HKLM": registry.LOCAL_MACHINE,
+	"HKU":  registry.USERS,
+	"HKCC": registry.CURRENT_CONFIG,
+}
+
+// openKey opens an existing key with the given access
+func openKey(path string, access uint32) (registry.Key, error) {
+	root, subkey, err := splitRegistryPath(path)
+	if err != nil {
+		return 0, err
+	}
+	return registry.OpenKey(nativeRoots[root], subkey, access|registry.WOW64_64KEY)
+}
+
+// createKey opens a key with the given access, creating it and its parents if needed
+func createKey(path string, access uint32) (registry.Key, error) {
+	root, subkey, err := splitRegistryPath(path)
+	if err != nil {
+		return 0, err
+	}
+	k, _, err := registry.CreateKey(nativeRoots[root], subkey, access|registry.WOW64_64KEY)
+	return k, err
+}
+
+// GetValue implements Registry
+func (NativeRegistry) GetValue(ctx context.Context, path, name string) (*RegistryValue, error) {
+	k, err := openKey(path, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer k.Close()
+
+	_, typ, err := k.GetValue(name, nil)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s\\%s: %w", path, name, err)
+	}
+	switch typ {
+	case registry.DWORD:
+		n, _, err := k.GetIntegerValue(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s\\%s: %w", path, name, err)
+		}
+		return DWORDValue(uint32(n)), nil
+	case registry.SZ, registry.EXPAND_SZ:
+		s, _, err := k.GetStringValue(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s\\%s: %w", path, name, err)
+		}
+		return StringValue(s), nil
+	default:
+		return nil, fmt.Errorf("%s\\%s has unsupported registry type %d", path, name, typ)
+	}
+}
+
+// SetValue implements Registry
+func (NativeRegistry) SetValue(ctx context.Context, path, name string, value *RegistryValue) error {
+	k, err := createKey(path, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer k.Close()
+
+	switch value.Kind {
+	case RegistryDWORD:
+		err = k.SetDWordValue(name, value.DWORD)
+	case RegistryString:
+		err = k.SetStringValue(name, value.String)
+	default:
+		return fmt.Errorf("unsupported registry value kind %q", value.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s\\%s: %w", path, name, err)
+	}
+	return nil
+}
+
+// DeleteValue implements Registry
+func (NativeRegistry) DeleteValue(ctx context.Context, path, name string) error {
+	k, err := openKey(path, registry.SET_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer k.Close()
+
+	if err := k.DeleteValue(name); err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("deleting %s\\%s: %w", path, name, err)
+	}
+	return nil
+}
+
+// EnsureKey implements Registry
+func (NativeRegistry) EnsureKey(ctx context.Context, path string) error {
+	k, err := createKey(path, registry.QUERY_VALUE)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	return k.Close()
+}
+
+// KeyExists implements Registry
+func (NativeRegistry) KeyExists(ctx context.Context, path string) (bool, error) {
+	k, err := openKey(path, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return true, k.Close()
+}
+
+// DeleteKey implements Registry
+func (NativeRegistry) DeleteKey(ctx context.Context, path string) error {
+	root, subkey, err := splitRegistryPath(path)
+	if err != nil {
+		return err
+	}
+	if subkey == "" {
+		return fmt.Errorf("cannot delete registry root %s", root)
+	}
+	k, err := openKey(path, registry.QUERY_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	info, err := k.Stat()
+	k.Close()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if info.SubKeyCount > 0 || info.ValueCount > 0 {
+		return fmt.Errorf("%s: %w", path, ErrKeyNotEmpty)
+	}
+
+	name, err := windows.UTF16PtrFromString(subkey)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procRegDeleteKeyExW.Call(uintptr(nativeRoots[root]), uintptr(unsafe.Pointer(name)), uintptr(registry.WOW64_64KEY), 0)
+	if err := windows.Errno(r); r != 0 && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("deleting %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cleaner
+
+// remedies maps a health check to the operations that address it.
+// Checks without an entry (uptime, memory, services) need manual action.
+var remedies = map[string][]string{
+	"disk_space": {"temp", "recycle", "disk"},
+	"temp_size":  {"temp"},
+	"dns":        {"flushdns"},
+}
+
+// PlanStep is an operation recommended by one or more health findings
+type PlanStep struct {
+	Operation     string   `json:"operation" yaml:"operation"`
+	Name          string   `json:"name" yaml:"name"`
+	Reasons       []string `json:"reasons" yaml:"reasons"`
+	RequiresAdmin bool     `json:"requires_admin,omitempty" yaml:"requires_admin,omitempty"`
+	Destructive   bool     `json:"destructive,omitempty" yaml:"destructive,omitempty"`
+}
+
+// PlanRemediation turns the problems in a health report into a list of operations, each
+// listed once with every finding that called for it, in registry order
+func PlanRemediation(health *HealthReport) []PlanStep {
+	reasons := make(map[string][]string)
+	for _, f := range health.Problems() {
+		ids := remedies[f.Check]
+		if len(ids) == 0 && f.Operation != "" {
+			ids = []string{f.Operation}
+		}
+		reason := f.Message
+		if f.Subject != "" {
+			reason = f.Subject + ": " + reason
+		}
+		for _, id := range ids {
+			reasons[id] = append(reasons[id], reason)
+		}
+	}
+
+	var plan []PlanStep
+	for _, op := range operations {
+		if len(reasons[op.ID]) == 0 {
+			continue
+		}
+		plan = append(plan, PlanStep{
+			Operation:     op.ID,
+			Name:          op.Name,
+			Reasons:       reasons[op.ID],
+			RequiresAdmin: op.RequiresAdmin,
+			Destructive:   op.Destructive,
+		})
+	}
+	return plan
+}
@@ -0,0 +1,97 @@
+package cleaner
+
+import (
+	"time"
+)
+
+// SkippedItem records an item an operation chose not to, or could not, remove
+type SkippedItem struct {
+	Path   string `json:"path" yaml:"path"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// CommandRecord captures a single external command executed by an operation
+type CommandRecord struct {
+	Command  string `json:"command" yaml:"command"`
+	ExitCode int    `json:"exit_code" yaml:"exit_code"`
+	Output   string `json:"output,omitempty" yaml:"output,omitempty"`
+	// DryRun is set when the command was only reported, not executed
+	DryRun bool `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+// Operation result statuses
+const (
+	StatusSuccess    = "success"
+	StatusFailed     = "failed"
+	StatusTimeout    = "timeout"
+	StatusCanceled   = "canceled"
+	StatusNeedsAdmin = "needs_admin"
+)
+
+// OperationResult describes what a single operation run actually achieved
+type OperationResult struct {
+	Operation    string        `json:"operation" yaml:"operation"`
+	Name         string        `json:"name" yaml:"name"`
+	Status       string        `json:"status" yaml:"status"`
+	DryRun       bool          `json:"dry_run" yaml:"dry_run"`
+	StartTime    time.Time     `json:"start_time" yaml:"start_time"`
+	EndTime      time.Time     `json:"end_time" yaml:"end_time"`
+	Duration     time.Duration `json:"duration_ns" yaml:"duration"`
+	BytesFreed   int64         `json:"bytes_freed" yaml:"bytes_freed"`
+	ItemsRemoved int           `json:"items_removed" yaml:"items_removed"`
+	// ItemsRestored and BytesRestored count files put back, e.g. from quarantine. They
+	// are kept apart from BytesFreed because a restore takes the space again.
+	ItemsRestored int             `json:"items_restored,omitempty" yaml:"items_restored,omitempty"`
+	BytesRestored int64           `json:"bytes_restored,omitempty" yaml:"bytes_restored,omitempty"`
+	ItemsSkipped  []SkippedItem   `json:"items_skipped,omitempty" yaml:"items_skipped,omitempty"`
+	Commands      []CommandRecord `json:"commands,omitempty" yaml:"commands,omitempty"`
+	Error         string          `json:"error,omitempty" yaml:"error,omitempty"`
+	// RebootRequired is set when the changes only take effect after a restart
+	RebootRequired bool `json:"reboot_required" yaml:"reboot_required"`
+}
+
+// Failed reports whether the operation did not complete successfully
+func (r *OperationResult) Failed() bool {
+	return r.Status != StatusSuccess
+}
+
+// newResult starts a result for an operation run with the given options
+func newResult(opts Options) *OperationResult {
+	return &OperationResult{DryRun: opts.DryRun, StartTime: time.Now()}
+}
+
+// finish stamps the end time and duration; operations defer it right after newResult
+func (r *OperationResult) finish() {
+	r.EndTime = time.Now()
+	r.Duration = r.EndTime.Sub(r.StartTime)
+}
+
+// recordCommand appends an executed (or dry-run) command to the result
+func (r *OperationResult) recordCommand(line string, result CommandResult, dryRun bool) {
+	if r == nil {
+		return
+	}
+	r.Commands = append(r.Commands, CommandRecord{
+		Command:  line,
+		ExitCode: result.ExitCode,
+		Output:   string(result.CombinedOutput()),
+		DryRun:   dryRun,
+	})
+}
+
+// removed counts an item deleted (or, in dry-run mode, that would be deleted)
+func (r *OperationResult) removed(size int64) {
+	r.ItemsRemoved++
+	r.BytesFreed += size
+}
+
+// restored counts an item put back (or, in dry-run mode, that would be put back)
+func (r *OperationResult) restored(size int64) {
+	r.ItemsRestored++
+	r.BytesRestored += size
+}
+
+// skip records an item that was left in place
+func (r *OperationResult) skip(path, reason string) {
+	r.ItemsSkipped = append(r.ItemsSkipped, SkippedItem{Path: path, Reason: reason})
+}
@@ -0,0 +1,89 @@
+package cleaner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandResult holds the captured outcome of an external command
+type CommandResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// CombinedOutput returns stdout followed by stderr, mirroring exec.Cmd.CombinedOutput
+func (r CommandResult) CombinedOutput() []byte {
+	return append(append([]byte{}, r.Stdout...), r.Stderr...)
+}
+
+// CommandRunner executes external commands on behalf of the cleaner operations
+type CommandRunner interface {
+	Run(ctx context.Context, name string, args ...string) (CommandResult, error)
+}
+
+// Runner is the CommandRunner used by every cleaner operation.
+// Replace it with a FakeRunner to exercise operations without touching the system.
+var Runner CommandRunner = ExecRunner{}
+
+// ExecRunner runs commands on the host using os/exec
+type ExecRunner struct{}
+
+// killGrace bounds how long Run waits for output pipes after the process tree is killed
+const killGrace = 5 * time.Second
+
+// Run executes the command and captures stdout, stderr and the exit code.
+// When ctx is done the command's whole process tree is killed.
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = killGrace
+	killTreeOnCancel(cmd)
+	err := cmd.Run()
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		err = fmt.Errorf("%s: %w", CommandLine(name, args...), ctxErr)
+	}
+
+	result := CommandResult{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		result.ExitCode = -1
+	}
+	return result, err
+}
+
+// CommandLine renders a command and its arguments as a single display string
+func CommandLine(name string, args ...string) string {
+	return strings.TrimSpace(name + " " + strings.Join(args, " "))
+}
+
+// runCommand executes a command that changes the system through Runner, echoing it
+// first in verbose mode and recording it in res. In dry-run mode the command is only reported.
+func runCommand(ctx context.Context, opts Options, res *OperationResult, name string, args ...string) (CommandResult, error) {
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would run: %s\n", CommandLine(name, args...))
+		res.recordCommand(CommandLine(name, args...), CommandResult{}, true)
+		return CommandResult{}, nil
+	}
+	return queryCommand(ctx, opts, res, name, args...)
+}
+
+// queryCommand executes a read-only command through Runner; it also runs in dry-run mode.
+// res may be nil for commands that are not part of an operation.
+func queryCommand(ctx context.Context, opts Options, res *OperationResult, name string, args ...string) (CommandResult, error) {
+	if opts.Verbose {
+		fmt.Fprintf(opts.Writer(), "[VERBOSE] Running command: %s\n", CommandLine(name, args...))
+	}
+	result, err := Runner.Run(ctx, name, args...)
+	res.recordCommand(CommandLine(name, args...), result, false)
+	return result, err
+}
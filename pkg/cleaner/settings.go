@@ -0,0 +1,145 @@
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// changeRegistryValue writes a registry value, or deletes it when value is nil. When a
+// change journal is attached, the previous value is captured and journaled before the write.
+func changeRegistryValue(ctx context.Context, opts Options, res *OperationResult, path, name string, value *RegistryValue) error {
+	if !opts.DryRun && opts.Journal != nil {
+		previous, err := WinRegistry.GetValue(ctx, path, name)
+		if err != nil {
+			return fmt.Errorf("failed to capture previous value of %s\\%s: %w", path, name, err)
+		}
+		// Keys the write creates are journaled first, so a rollback removes them after the value
+		if value != nil {
+			created, err := missingKeys(ctx, path)
+			if err != nil {
+				return fmt.Errorf("failed to check registry key %s: %w", path, err)
+			}
+			for _, key := range created {
+				if err := opts.Journal.Record(Change{Kind: ChangeRegistryKey, Path: key}); err != nil {
+					return fmt.Errorf("failed to write change journal: %w", err)
+				}
+			}
+		}
+		change := Change{Kind: ChangeRegistry, Path: path, Name: name, Previous: previous, New: value}
+		if err := opts.Journal.Record(change); err != nil {
+			return fmt.Errorf("failed to write change journal: %w", err)
+		}
+	}
+	if value == nil {
+		return deleteRegistryValue(ctx, opts, res, path, name)
+	}
+	return setRegistryValue(ctx, opts, res, path, name, value)
+}
+
+// missingKeys returns the keys on path that do not exist yet, outermost first
+func missingKeys(ctx context.Context, path string) ([]string, error) {
+	root, subkey, err := splitRegistryPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for subkey != "" {
+		key := root + `:\` + subkey
+		exists, err := WinRegistry.KeyExists(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			break
+		}
+		missing = append([]string{key}, missing...)
+		i := strings.LastIndex(subkey, `\`)
+		if i < 0 {
+			break
+		}
+		subkey = subkey[:i]
+	}
+	return missing, nil
+}
+
+// changePowerScheme activates a power scheme, journaling the previously active one
+func changePowerScheme(ctx context.Context, opts Options, res *OperationResult, scheme string) error {
+	if !opts.DryRun && opts.Journal != nil {
+		previous, err := activePowerScheme(ctx, opts, res)
+		if err != nil {
+			return fmt.Errorf("failed to capture active power scheme: %w", err)
+		}
+		if err := opts.Journal.Record(Change{Kind: ChangePowerScheme, PreviousScheme: previous}); err != nil {
+			return fmt.Errorf("failed to write change journal: %w", err)
+		}
+	}
+	return setPowerScheme(ctx, opts, res, scheme)
+}
+
+// setRegistryValue writes a registry value through WinRegistry, creating its key if needed.
+// In dry-run mode the write is only reported.
+func setRegistryValue(ctx context.Context, opts Options, res *OperationResult, path, name string, value *RegistryValue) error {
+	action := fmt.Sprintf("set registry value %s\\%s = %s", path, name, value.Display())
+	if reportRegistryChange(opts, res, action) {
+		return nil
+	}
+	return WinRegistry.SetValue(ctx, path, name, value)
+}
+
+// deleteRegistryValue removes a registry value through WinRegistry; a value that does not
+// exist is not an error. In dry-run mode the deletion is only reported.
+func deleteRegistryValue(ctx context.Context, opts Options, res *OperationResult, path, name string) error {
+	action := fmt.Sprintf("delete registry value %s\\%s", path, name)
+	if reportRegistryChange(opts, res, action) {
+		return nil
+	}
+	return WinRegistry.DeleteValue(ctx, path, name)
+}
+
+// deleteRegistryKey removes an empty registry key through WinRegistry. In dry-run mode
+// the deletion is only reported.
+func deleteRegistryKey(ctx context.Context, opts Options, res *OperationResult, path string) error {
+	action := fmt.Sprintf("delete registry key %s", path)
+	if reportRegistryChange(opts, res, action) {
+		return nil
+	}
+	return WinRegistry.DeleteKey(ctx, path)
+}
+
+// reportRegistryChange echoes and records a registry change, and reports whether it is
+// a dry run, in which case the change must not be made
+func reportRegistryChange(opts Options, res *OperationResult, action string) bool {
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would %s\n", action)
+	} else if opts.Verbose {
+		fmt.Fprintf(opts.Writer(), "[VERBOSE] Registry: %s\n", action)
+	}
+	res.recordCommand(action, CommandResult{}, opts.DryRun)
+	return opts.DryRun
+}
+
+var schemeGUID = regexp.MustCompile(`[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`)
+
+// activePowerScheme returns the GUID of the active power scheme
+func activePowerScheme(ctx context.Context, opts Options, res *OperationResult) (string, error) {
+	result, err := queryCommand(ctx, opts, res, "powercfg", "/getactivescheme")
+	if err != nil {
+		return "", fmt.Errorf("%v\nOutput: %s", err, string(result.CombinedOutput()))
+	}
+	guid := schemeGUID.FindString(string(result.Stdout))
+	if guid == "" {
+		return "", fmt.Errorf("unexpected powercfg output %q", strings.TrimSpace(string(result.Stdout)))
+	}
+	return strings.ToLower(guid), nil
+}
+
+// setPowerScheme activates a power scheme by alias (e.g. SCHEME_BALANCED) or GUID
+func setPowerScheme(ctx context.Context, opts Options, res *OperationResult, scheme string) error {
+	result, err := runCommand(ctx, opts, res, "powershell", "-Command", "powercfg /setactive "+scheme)
+	if err != nil {
+		return fmt.Errorf("%v\nOutput: %s", err, string(result.CombinedOutput()))
+	}
+	return nil
+}
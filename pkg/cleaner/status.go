@@ -1,128 +1,203 @@
 package cleaner
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
+	"sort"
+	"sync"
+	"time"
 )
 
-// SystemStatus represents the overall system status information
+// SystemStatus represents the overall system status information. Each section is
+// collected independently; a section that could not be collected is left empty and
+// its error is recorded in Errors.
 type SystemStatus struct {
-	DiskSpace      map[string]DiskInfo
-	WindowsVersion string
-	LastBootTime   string
+	DiskSpace      map[string]DiskInfo `json:"disk_space" yaml:"disk_space"`
+	WindowsVersion string              `json:"windows_version" yaml:"windows_version"`
+	// LastBootTime is the zero time when it could not be determined
+	LastBootTime    time.Time      `json:"last_boot_time" yaml:"last_boot_time"`
+	Uptime          time.Duration  `json:"uptime_ns" yaml:"uptime"`
+	Memory          *MemoryInfo    `json:"memory,omitempty" yaml:"memory,omitempty"`
+	CPULoadPercent  *float64       `json:"cpu_load_percent,omitempty" yaml:"cpu_load_percent,omitempty"`
+	PageFiles       []PageFileInfo `json:"page_files,omitempty" yaml:"page_files,omitempty"`
+	PendingReboot   *PendingReboot `json:"pending_reboot,omitempty" yaml:"pending_reboot,omitempty"`
+	StoppedServices []ServiceInfo  `json:"stopped_services,omitempty" yaml:"stopped_services,omitempty"`
+	// TempFolderBytes is the total size of the temporary directories
+	TempFolderBytes *int64 `json:"temp_folder_bytes,omitempty" yaml:"temp_folder_bytes,omitempty"`
+	// DNS reports whether DNSProbeHost could be resolved
+	DNS *DNSCheck `json:"dns,omitempty" yaml:"dns,omitempty"`
+	// Errors maps a collector name (disks, os, boot, memory, cpu, page_files,
+	// pending_reboot, services, temp, dns) to the reason it failed
+	Errors map[string]string `json:"errors,omitempty" yaml:"errors,omitempty"`
 }
 
 // DiskInfo contains information about a disk drive
 type DiskInfo struct {
-	TotalSize   string
-	FreeSpace   string
-	UsedSpace   string
-	UsedPercent string
+	TotalBytes  uint64  `json:"total_bytes" yaml:"total_bytes"`
+	FreeBytes   uint64  `json:"free_bytes" yaml:"free_bytes"`
+	UsedBytes   uint64  `json:"used_bytes" yaml:"used_bytes"`
+	UsedPercent float64 `json:"used_percent" yaml:"used_percent"`
+	FileSystem  string  `json:"file_system,omitempty" yaml:"file_system,omitempty"`
+	DriveType   string  `json:"drive_type,omitempty" yaml:"drive_type,omitempty"`
 }
 
-// GetSystemStatus retrieves the current system status
-func GetSystemStatus() (*SystemStatus, error) {
-	status := &SystemStatus{
-		DiskSpace: make(map[string]DiskInfo),
-	}
-
-	// Get disk space information
-	if err := getDiskSpace(status); err != nil {
-		return nil, fmt.Errorf("failed to get disk space: %w", err)
+// Drives returns the drive names in DiskSpace in sorted order
+func (s *SystemStatus) Drives() []string {
+	drives := make([]string, 0, len(s.DiskSpace))
+	for drive := range s.DiskSpace {
+		drives = append(drives, drive)
 	}
-
-	// Get Windows version
-	if ver, err := getWindowsVersion(); err == nil {
-		status.WindowsVersion = ver
-	}
-
-	// Get last boot time
-	if bootTime, err := getLastBootTime(); err == nil {
-		status.LastBootTime = bootTime
-	}
-
-	return status, nil
+	sort.Strings(drives)
+	return drives
 }
 
-// getDiskSpace retrieves disk space information for all drives
-func getDiskSpace(status *SystemStatus) error {
-	cmd := exec.Command("powershell", "-Command",
-		"Get-WmiObject -Class Win32_LogicalDisk | Select-Object DeviceID, Size, FreeSpace | ConvertTo-Csv -NoTypeInformation")
-
-	output, err := cmd.Output()
-	if err != nil {
-		return err
+// GetSystemStatus retrieves the current system status from SystemInfo. The collectors
+// run concurrently and fail independently; only cancellation of ctx returns an error.
+// Collectors that are unsupported on this platform are silently left empty.
+func GetSystemStatus(ctx context.Context) (*SystemStatus, error) {
+	status := &SystemStatus{
+		DiskSpace: make(map[string]DiskInfo),
 	}
 
-	lines := strings.Split(string(output), "\r\n")
-	if len(lines) < 2 {
-		return fmt.Errorf("unexpected output format")
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	collect := func(name string, collector func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := collector()
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+				if status.Errors == nil {
+					status.Errors = make(map[string]string)
+				}
+				status.Errors[name] = err.Error()
+			}
+		}()
 	}
 
-	// Skip header line
-	for _, line := range lines[1:] {
-		if line == "" {
-			continue
+	collect("disks", func() error {
+		volumes, err := SystemInfo.Volumes(ctx)
+		if err != nil {
+			return err
 		}
-
-		// Parse CSV line
-		parts := strings.Split(line, ",")
-		if len(parts) >= 3 {
-			drive := strings.Trim(parts[0], "\"")
-			sizeStr := strings.Trim(parts[1], "\"")
-			freeStr := strings.Trim(parts[2], "\"")
-
-			// Skip drives with empty size
-			if sizeStr == "" {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, volume := range volumes {
+			if volume.Total == 0 {
 				continue
 			}
-
-			// Parse size values
-			size, err1 := strconv.ParseInt(sizeStr, 10, 64)
-			free, err2 := strconv.ParseInt(freeStr, 10, 64)
-
-			if err1 == nil && err2 == nil && size > 0 {
-				used := size - free
-				usedPercent := float64(used) / float64(size) * 100
-
-				status.DiskSpace[drive] = DiskInfo{
-					TotalSize:   formatBytes(float64(size)),
-					FreeSpace:   formatBytes(float64(free)),
-					UsedSpace:   formatBytes(float64(used)),
-					UsedPercent: fmt.Sprintf("%.1f%%", usedPercent),
-				}
+			used := volume.Total - min(volume.Free, volume.Total)
+			status.DiskSpace[volume.Drive] = DiskInfo{
+				TotalBytes:  volume.Total,
+				FreeBytes:   volume.Free,
+				UsedBytes:   used,
+				UsedPercent: float64(used) / float64(volume.Total) * 100,
+				FileSystem:  volume.FileSystem,
+				DriveType:   volume.Type,
 			}
 		}
-	}
-
-	return nil
-}
-
-// getWindowsVersion retrieves the Windows version
-func getWindowsVersion() (string, error) {
-	cmd := exec.Command("powershell", "-Command", "(Get-WmiObject -class Win32_OperatingSystem).Caption")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(output)), nil
-}
-
-// getLastBootTime retrieves the last system boot time
-func getLastBootTime() (string, error) {
-	cmd := exec.Command("powershell", "-Command",
-		"(Get-CimInstance -ClassName Win32_OperatingSystem).LastBootUpTime.ToString('yyyy-MM-dd HH:mm:ss')")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(output)), nil
+		return nil
+	})
+	collect("os", func() error {
+		ver, err := SystemInfo.OSVersion(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		status.WindowsVersion = ver
+		return err
+	})
+	collect("boot", func() error {
+		bootTime, err := SystemInfo.BootTime(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status.LastBootTime = bootTime
+		status.Uptime = time.Since(bootTime).Truncate(time.Second)
+		return nil
+	})
+	collect("memory", func() error {
+		memory, err := SystemInfo.Memory(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status.Memory = &memory
+		return nil
+	})
+	collect("cpu", func() error {
+		load, err := SystemInfo.CPULoad(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status.CPULoadPercent = &load
+		return nil
+	})
+	collect("page_files", func() error {
+		files, err := SystemInfo.PageFiles(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		status.PageFiles = files
+		return err
+	})
+	collect("pending_reboot", func() error {
+		flags, err := SystemInfo.PendingReboot(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status.PendingReboot = &flags
+		return nil
+	})
+	collect("services", func() error {
+		services, err := SystemInfo.StoppedAutoStartServices(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		status.StoppedServices = services
+		return err
+	})
+	collect("temp", func() error {
+		size, err := TempFolderSize(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status.TempFolderBytes = &size
+		return nil
+	})
+	collect("dns", func() error {
+		probeCtx, cancel := context.WithTimeout(ctx, dnsProbeTimeout)
+		defer cancel()
+		// A failed lookup is the finding itself, not a collection error
+		check := &DNSCheck{Host: DNSProbeHost}
+		if err := SystemInfo.ResolveHost(probeCtx, DNSProbeHost); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			check.Error = err.Error()
+		} else {
+			check.Resolved = true
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		status.DNS = check
+		return nil
+	})
+
+	wg.Wait()
+	return status, ctx.Err()
 }
 
-// formatBytes formats bytes to a human-readable string
-func formatBytes(bytes float64) string {
+// FormatBytes formats bytes to a human-readable string
+func FormatBytes(bytes float64) string {
 	const unit = 1024.0
 	if bytes < unit {
 		return fmt.Sprintf("%.0f B", bytes)
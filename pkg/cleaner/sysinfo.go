@@ -0,0 +1,350 @@
+package cleaner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VolumeInfo is the capacity of one mounted volume
+type VolumeInfo struct {
+	// Drive is the drive letter ("C:") on Windows or the mount point elsewhere
+	Drive string
+	Total uint64
+	Free  uint64
+	// FileSystem is e.g. "NTFS", "FAT32" or "ext4"
+	FileSystem string
+	// Type is fixed, removable, network, cdrom or ramdisk
+	Type string
+}
+
+// MemoryInfo is physical memory and commit charge usage, in bytes
+type MemoryInfo struct {
+	TotalBytes       uint64  `json:"total_bytes" yaml:"total_bytes"`
+	AvailableBytes   uint64  `json:"available_bytes" yaml:"available_bytes"`
+	UsedPercent      float64 `json:"used_percent" yaml:"used_percent"`
+	CommitLimitBytes uint64  `json:"commit_limit_bytes" yaml:"commit_limit_bytes"`
+	CommitUsedBytes  uint64  `json:"commit_used_bytes" yaml:"commit_used_bytes"`
+	CommitPercent    float64 `json:"commit_percent" yaml:"commit_percent"`
+}
+
+// PageFileInfo is the usage of one page file (or swap area), in bytes
+type PageFileInfo struct {
+	Path       string `json:"path" yaml:"path"`
+	TotalBytes uint64 `json:"total_bytes" yaml:"total_bytes"`
+	UsedBytes  uint64 `json:"used_bytes" yaml:"used_bytes"`
+	PeakBytes  uint64 `json:"peak_bytes,omitempty" yaml:"peak_bytes,omitempty"`
+}
+
+// PendingReboot reports the registry flags Windows sets when a restart is needed
+type PendingReboot struct {
+	// ComponentServicing is set by CBS after servicing stack or feature changes
+	ComponentServicing bool `json:"component_servicing" yaml:"component_servicing"`
+	// WindowsUpdate is set when installed updates need a restart
+	WindowsUpdate bool `json:"windows_update" yaml:"windows_update"`
+	// FileRenames is set when files are queued to be replaced at boot (PendingFileRenameOperations)
+	FileRenames bool `json:"file_renames" yaml:"file_renames"`
+}
+
+// Required reports whether any pending-reboot flag is set
+func (p PendingReboot) Required() bool {
+	return p.ComponentServicing || p.WindowsUpdate || p.FileRenames
+}
+
+// DNSCheck is the outcome of resolving a well-known host name
+type DNSCheck struct {
+	Host     string `json:"host" yaml:"host"`
+	Resolved bool   `json:"resolved" yaml:"resolved"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ServiceInfo identifies a service
+type ServiceInfo struct {
+	Name         string `json:"name" yaml:"name"`
+	DisplayName  string `json:"display_name" yaml:"display_name"`
+	DelayedStart bool   `json:"delayed_start" yaml:"delayed_start"`
+}
+
+// SystemInfoProvider collects the raw facts behind GetSystemStatus. Methods that have
+// no equivalent on the current platform return an error wrapping errors.ErrUnsupported.
+type SystemInfoProvider interface {
+	// Volumes lists fixed and removable volumes that have media
+	Volumes(ctx context.Context) ([]VolumeInfo, error)
+	// OSVersion returns a human-readable operating system name and version
+	OSVersion(ctx context.Context) (string, error)
+	// BootTime returns when the system last started
+	BootTime(ctx context.Context) (time.Time, error)
+	// Memory returns physical memory and commit charge usage
+	Memory(ctx context.Context) (MemoryInfo, error)
+	// CPULoad samples overall processor utilization, as a percentage, over a short interval
+	CPULoad(ctx context.Context) (float64, error)
+	// PageFiles lists the configured page files and their usage
+	PageFiles(ctx context.Context) ([]PageFileInfo, error)
+	// PendingReboot reads the pending-reboot flags
+	PendingReboot(ctx context.Context) (PendingReboot, error)
+	// StoppedAutoStartServices lists services set to start automatically that are not
+	// running, leaving out those expected to stop on their own: trigger-start services,
+	// and delayed-start services that exited cleanly
+	StoppedAutoStartServices(ctx context.Context) ([]ServiceInfo, error)
+	// ResolveHost looks up a host name through the system resolver
+	ResolveHost(ctx context.Context, host string) error
+	// IdleTime returns how long it has been since the last keyboard or mouse input
+	IdleTime(ctx context.Context) (time.Duration, error)
+	// OnACPower reports whether the system runs on mains power; systems without a
+	// battery always do
+	OnACPower(ctx context.Context) (bool, error)
+}
+
+// DNSProbeHost is the host name GetSystemStatus resolves to check name resolution
+var DNSProbeHost = "www.microsoft.com"
+
+// dnsProbeTimeout bounds the name resolution check
+const dnsProbeTimeout = 5 * time.Second
+
+// cpuSampleInterval is how long CPULoad measures processor time
+const cpuSampleInterval = 500 * time.Millisecond
+
+// SleepContext waits for d, returning early with ctx's error if it is canceled
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ResolveHost implements SystemInfoProvider
+func (NativeSystemInfo) ResolveHost(ctx context.Context, host string) error {
+	_, err := net.DefaultResolver.LookupHost(ctx, host)
+	return err
+}
+
+// SystemInfo is the provider used by GetSystemStatus; tests replace it with a FakeSystemInfo
+var SystemInfo SystemInfoProvider = NativeSystemInfo{}
+
+// FakeSystemInfo is a SystemInfoProvider that returns fixed values
+type FakeSystemInfo struct {
+	VolumeList   []VolumeInfo
+	Version      string
+	Boot         time.Time
+	Mem          MemoryInfo
+	CPU          float64
+	PageFileList []PageFileInfo
+	Reboot       PendingReboot
+	Services     []ServiceInfo
+	// DNSErr is returned by ResolveHost
+	DNSErr error
+	Idle   time.Duration
+	// OnBattery is the inverse of OnACPower, so that the zero value is on AC power
+	OnBattery bool
+	// Err, when set, is returned by every method
+	Err error
+}
+
+// Volumes implements SystemInfoProvider
+func (f FakeSystemInfo) Volumes(ctx context.Context) ([]VolumeInfo, error) {
+	return f.VolumeList, f.Err
+}
+
+// OSVersion implements SystemInfoProvider
+func (f FakeSystemInfo) OSVersion(ctx context.Context) (string, error) {
+	return f.Version, f.Err
+}
+
+// BootTime implements SystemInfoProvider
+func (f FakeSystemInfo) BootTime(ctx context.Context) (time.Time, error) {
+	return f.Boot, f.Err
+}
+
+// Memory implements SystemInfoProvider
+func (f FakeSystemInfo) Memory(ctx context.Context) (MemoryInfo, error) {
+	return f.Mem, f.Err
+}
+
+// CPULoad implements SystemInfoProvider
+func (f FakeSystemInfo) CPULoad(ctx context.Context) (float64, error) {
+	return f.CPU, f.Err
+}
+
+// PageFiles implements SystemInfoProvider
+func (f FakeSystemInfo) PageFiles(ctx context.Context) ([]PageFileInfo, error) {
+	return f.PageFileList, f.Err
+}
+
+// PendingReboot implements SystemInfoProvider
+func (f FakeSystemInfo) PendingReboot(ctx context.Context) (PendingReboot, error) {
+	return f.Reboot, f.Err
+}
+
+// StoppedAutoStartServices implements SystemInfoProvider
+func (f FakeSystemInfo) StoppedAutoStartServices(ctx context.Context) ([]ServiceInfo, error) {
+	return f.Services, f.Err
+}
+
+// ResolveHost implements SystemInfoProvider
+func (f FakeSystemInfo) ResolveHost(ctx context.Context, host string) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	return f.DNSErr
+}
+
+// IdleTime implements SystemInfoProvider
+func (f FakeSystemInfo) IdleTime(ctx context.Context) (time.Duration, error) {
+	return f.Idle, f.Err
+}
+
+// OnACPower implements SystemInfoProvider
+func (f FakeSystemInfo) OnACPower(ctx context.Context) (bool, error) {
+	return !f.OnBattery, f.Err
+}
+
+// newMemoryInfo fills in the percentages of a MemoryInfo
+func newMemoryInfo(total, available, commitLimit, commitUsed uint64) MemoryInfo {
+	info := MemoryInfo{
+		TotalBytes:       total,
+		AvailableBytes:   available,
+		CommitLimitBytes: commitLimit,
+		CommitUsedBytes:  commitUsed,
+	}
+	if total > 0 {
+		info.UsedPercent = float64(total-min(available, total)) / float64(total) * 100
+	}
+	if commitLimit > 0 {
+		info.CommitPercent = float64(commitUsed) / float64(commitLimit) * 100
+	}
+	return info
+}
+
+// parseOSRelease returns the PRETTY_NAME (or NAME and VERSION) from an os-release file
+func parseOSRelease(data string) (string, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'`)
+		}
+		fields[key] = value
+	}
+	if name := fields["PRETTY_NAME"]; name != "" {
+		return name, nil
+	}
+	if name := strings.TrimSpace(fields["NAME"] + " " + fields["VERSION"]); name != "" {
+		return name, nil
+	}
+	return "", errors.New("os-release has no NAME")
+}
+
+// parseUptime returns the uptime from the first field of /proc/uptime
+func parseUptime(data string) (time.Duration, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return 0, errors.New("empty uptime")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid uptime %q", fields[0])
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// mountInfo is a mounted file system listed in /proc/mounts
+type mountInfo struct {
+	Path       string
+	FileSystem string
+}
+
+// parseMounts returns the block-device file systems listed in /proc/mounts
+func parseMounts(data string) []mountInfo {
+	var mounts []mountInfo
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		// /proc/mounts escapes spaces in paths as \040
+		mounts = append(mounts, mountInfo{Path: strings.ReplaceAll(fields[1], `\040`, " "), FileSystem: fields[2]})
+	}
+	return mounts
+}
+
+// parseMeminfo reads /proc/meminfo into a MemoryInfo
+func parseMeminfo(data string) (MemoryInfo, error) {
+	kb := make(map[string]uint64)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		fields := strings.Fields(rest)
+		if !ok || len(fields) == 0 {
+			continue
+		}
+		if n, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			kb[key] = n * 1024
+		}
+	}
+	if kb["MemTotal"] == 0 {
+		return MemoryInfo{}, errors.New("meminfo has no MemTotal")
+	}
+	return newMemoryInfo(kb["MemTotal"], kb["MemAvailable"], kb["CommitLimit"], kb["Committed_AS"]), nil
+}
+
+// parseCPUStat returns the busy and total jiffies from the aggregate "cpu" line of /proc/stat
+func parseCPUStat(data string) (busy, total uint64, err error) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		var idle uint64
+		for i, field := range fields[1:] {
+			n, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid cpu time %q", field)
+			}
+			total += n
+			// idle and iowait
+			if i == 3 || i == 4 {
+				idle += n
+			}
+		}
+		return total - idle, total, nil
+	}
+	return 0, 0, errors.New("stat has no cpu line")
+}
+
+// parseSwaps reads /proc/swaps, whose sizes are in KiB
+func parseSwaps(data string) []PageFileInfo {
+	var swaps []PageFileInfo
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[0] == "Filename" {
+			continue
+		}
+		size, err1 := strconv.ParseUint(fields[2], 10, 64)
+		used, err2 := strconv.ParseUint(fields[3], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		swaps = append(swaps, PageFileInfo{Path: fields[0], TotalBytes: size * 1024, UsedBytes: used * 1024})
+	}
+	return swaps
+}
@@ -0,0 +1,148 @@
+//go:build !windows
+
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// NativeSystemInfo reads system information from /proc, /etc/os-release and statfs,
+// so that status works when developing on Linux
+type NativeSystemInfo struct{}
+
+// Volumes implements SystemInfoProvider
+func (NativeSystemInfo) Volumes(ctx context.Context) ([]VolumeInfo, error) {
+	mounts := []mountInfo{{Path: "/"}}
+	if data, err := os.ReadFile("/proc/mounts"); err == nil {
+		if found := parseMounts(string(data)); len(found) > 0 {
+			mounts = found
+		}
+	}
+
+	var volumes []VolumeInfo
+	for _, mount := range mounts {
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(mount.Path, &fs); err != nil || fs.Blocks == 0 {
+			continue
+		}
+		volumes = append(volumes, VolumeInfo{
+			Drive:      mount.Path,
+			Total:      uint64(fs.Blocks) * uint64(fs.Bsize),
+			Free:       uint64(fs.Bavail) * uint64(fs.Bsize),
+			FileSystem: mount.FileSystem,
+			Type:       "fixed",
+		})
+	}
+	return volumes, nil
+}
+
+// OSVersion implements SystemInfoProvider
+func (NativeSystemInfo) OSVersion(ctx context.Context) (string, error) {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return "", err
+	}
+	return parseOSRelease(string(data))
+}
+
+// BootTime implements SystemInfoProvider
+func (NativeSystemInfo) BootTime(ctx context.Context) (time.Time, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return time.Time{}, err
+	}
+	uptime, err := parseUptime(string(data))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-uptime).Truncate(time.Second), nil
+}
+
+// Memory implements SystemInfoProvider
+func (NativeSystemInfo) Memory(ctx context.Context) (MemoryInfo, error) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return MemoryInfo{}, err
+	}
+	return parseMeminfo(string(data))
+}
+
+// CPULoad implements SystemInfoProvider
+func (NativeSystemInfo) CPULoad(ctx context.Context) (float64, error) {
+	sample := func() (uint64, uint64, error) {
+		data, err := os.ReadFile("/proc/stat")
+		if err != nil {
+			return 0, 0, err
+		}
+		return parseCPUStat(string(data))
+	}
+	busy1, total1, err := sample()
+	if err != nil {
+		return 0, err
+	}
+	if err := SleepContext(ctx, cpuSampleInterval); err != nil {
+		return 0, err
+	}
+	busy2, total2, err := sample()
+	if err != nil {
+		return 0, err
+	}
+	// iowait may go backwards, so busy time is not strictly monotonic
+	if total2 <= total1 || busy2 < busy1 {
+		return 0, nil
+	}
+	return float64(busy2-busy1) / float64(total2-total1) * 100, nil
+}
+
+// PageFiles implements SystemInfoProvider; swap areas stand in for page files
+func (NativeSystemInfo) PageFiles(ctx context.Context) ([]PageFileInfo, error) {
+	data, err := os.ReadFile("/proc/swaps")
+	if err != nil {
+		return nil, err
+	}
+	return parseSwaps(string(data)), nil
+}
+
+// PendingReboot implements SystemInfoProvider
+func (NativeSystemInfo) PendingReboot(ctx context.Context) (PendingReboot, error) {
+	return PendingReboot{}, fmt.Errorf("pending reboot flags: %w", errors.ErrUnsupported)
+}
+
+// StoppedAutoStartServices implements SystemInfoProvider
+func (NativeSystemInfo) StoppedAutoStartServices(ctx context.Context) ([]ServiceInfo, error) {
+	return nil, fmt.Errorf("service status: %w", errors.ErrUnsupported)
+}
+
+// IdleTime implements SystemInfoProvider
+func (NativeSystemInfo) IdleTime(ctx context.Context) (time.Duration, error) {
+	return 0, fmt.Errorf("idle time: %w", errors.ErrUnsupported)
+}
+
+// OnACPower implements SystemInfoProvider using /sys/class/power_supply: mains power
+// that is online wins, otherwise a discharging battery means battery power
+func (NativeSystemInfo) OnACPower(ctx context.Context) (bool, error) {
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+	read := func(dir, name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return strings.TrimSpace(string(data))
+	}
+	discharging := false
+	for _, dir := range supplies {
+		switch read(dir, "type") {
+		case "Mains":
+			if read(dir, "online") == "1" {
+				return true, nil
+			}
+		case "Battery":
+			discharging = discharging || read(dir, "status") == "Discharging"
+		}
+	}
+	return !discharging, nil
+}
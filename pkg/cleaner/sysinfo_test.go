@@ -0,0 +1,169 @@
+package cleaner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "pretty name", data: "NAME=\"Ubuntu\"\nVERSION=\"24.04 LTS (Noble Numbat)\"\nPRETTY_NAME=\"Ubuntu 24.04 LTS\"\n", want: "Ubuntu 24.04 LTS"},
+		{name: "name and version", data: "NAME=Fedora\nVERSION='40 (Workstation Edition)'\n", want: "Fedora 40 (Workstation Edition)"},
+		{name: "name only", data: "# comment\nNAME=\"Alpine Linux\"\n\n", want: "Alpine Linux"},
+		{name: "escaped quotes", data: `PRETTY_NAME="Debian \"sid\""`, want: `Debian "sid"`},
+		{name: "comments are ignored", data: "#PRETTY_NAME=Old\nNAME=Arch\n", want: "Arch"},
+		{name: "no name", data: "ID=unknown\n", wantErr: true},
+		{name: "empty", data: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOSRelease(tt.data)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseOSRelease() = %q, %v; want %q, error: %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseUptime(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{data: "350735.47 234388.90\n", want: 350735*time.Second + 470*time.Millisecond},
+		{data: "12 3", want: 12 * time.Second},
+		{data: "", wantErr: true},
+		{data: "soon 1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseUptime(tt.data)
+		if (err != nil) != tt.wantErr || got.Round(time.Millisecond) != tt.want {
+			t.Errorf("parseUptime(%q) = %v, %v; want %v, error: %v", tt.data, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	data := `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+tmpfs /run tmpfs rw,nosuid,nodev 0 0
+/dev/nvme0n1p1 /boot/efi vfat rw,relatime 0 0
+/dev/sdb1 /media/My\040Disk exfat rw 0 0
+/dev/nvme0n1p2 /var/snap ext4 rw,relatime 0 0
+short line
+`
+	want := []mountInfo{
+		{Path: "/", FileSystem: "ext4"},
+		{Path: "/boot/efi", FileSystem: "vfat"},
+		{Path: "/media/My Disk", FileSystem: "exfat"},
+	}
+	if got := parseMounts(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMounts() = %+v, want %+v", got, want)
+	}
+	if got := parseMounts(""); got != nil {
+		t.Errorf("parseMounts(\"\") = %+v, want none", got)
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    MemoryInfo
+		wantErr bool
+	}{
+		{
+			name: "full",
+			data: "MemTotal:       16000000 kB\nMemFree:         1000000 kB\nMemAvailable:    4000000 kB\n" +
+				"CommitLimit:    20000000 kB\nCommitted_AS:    5000000 kB\nHugePages_Total:       0\n",
+			want: MemoryInfo{
+				TotalBytes: 16000000 * 1024, AvailableBytes: 4000000 * 1024, UsedPercent: 75,
+				CommitLimitBytes: 20000000 * 1024, CommitUsedBytes: 5000000 * 1024, CommitPercent: 25,
+			},
+		},
+		{
+			name: "no commit figures",
+			data: "MemTotal: 1000 kB\nMemAvailable: 1000 kB\n",
+			want: MemoryInfo{TotalBytes: 1000 * 1024, AvailableBytes: 1000 * 1024},
+		},
+		{
+			name: "available above total",
+			data: "MemTotal: 1000 kB\nMemAvailable: 2000 kB\n",
+			want: MemoryInfo{TotalBytes: 1000 * 1024, AvailableBytes: 2000 * 1024},
+		},
+		{name: "no total", data: "MemFree: 1000 kB\n", wantErr: true},
+		{name: "garbage", data: "MemTotal: lots\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMeminfo(tt.data)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseMeminfo() = %+v, %v; want %+v, error: %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCPUStat(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantBusy  uint64
+		wantTotal uint64
+		wantErr   bool
+	}{
+		{
+			name:     "aggregate line",
+			data:     "cpu  100 5 50 800 20 3 2 0 0 0\ncpu0 50 2 25 400 10 1 1 0 0 0\nintr 12345\n",
+			wantBusy: 160, wantTotal: 980,
+		},
+		{name: "old kernels with four fields", data: "cpu 10 0 10 80\n", wantBusy: 20, wantTotal: 100},
+		{name: "per-cpu lines only", data: "cpu0 1 2 3 4 5\n", wantErr: true},
+		{name: "invalid time", data: "cpu 1 2 x 4 5\n", wantErr: true},
+		{name: "empty", data: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			busy, total, err := parseCPUStat(tt.data)
+			if (err != nil) != tt.wantErr || busy != tt.wantBusy || total != tt.wantTotal {
+				t.Errorf("parseCPUStat() = %d, %d, %v; want %d, %d, error: %v", busy, total, err, tt.wantBusy, tt.wantTotal, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSwaps(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []PageFileInfo
+	}{
+		{
+			name: "partition and file",
+			data: "Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n" +
+				"/dev/dm-1                               partition\t8388604\t\t1024\t\t-2\n" +
+				"/swapfile                               file\t\t2097148\t\t0\t\t-3\n",
+			want: []PageFileInfo{
+				{Path: "/dev/dm-1", TotalBytes: 8388604 * 1024, UsedBytes: 1024 * 1024},
+				{Path: "/swapfile", TotalBytes: 2097148 * 1024},
+			},
+		},
+		{name: "no swap", data: "Filename\tType\tSize\tUsed\tPriority\n"},
+		{name: "malformed lines are skipped", data: "/swapfile file big 0 -2\n/swap2 file\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSwaps(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSwaps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
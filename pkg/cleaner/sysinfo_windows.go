@@ -0,0 +1,362 @@
+//go:build windows
+
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// NativeSystemInfo queries Windows directly through the Win32 API and the registry
+type NativeSystemInfo struct{}
+
+var (
+	kernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+	procGetTickCount64       = kernel32.NewProc("GetTickCount64")
+	procGlobalMemoryStatusEx = kernel32.NewProc("GlobalMemoryStatusEx")
+	procGetSystemTimes       = kernel32.NewProc("GetSystemTimes")
+	procGetTickCount         = kernel32.NewProc("GetTickCount")
+	procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
+
+	user32               = windows.NewLazySystemDLL("user32.dll")
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+)
+
+// driveTypes names the GetDriveType results reported in VolumeInfo.Type
+var driveTypes = map[uint32]string{
+	windows.DRIVE_REMOVABLE: "removable",
+	windows.DRIVE_FIXED:     "fixed",
+	windows.DRIVE_REMOTE:    "network",
+	windows.DRIVE_CDROM:     "cdrom",
+	windows.DRIVE_RAMDISK:   "ramdisk",
+}
+
+// Volumes implements SystemInfoProvider
+func (NativeSystemInfo) Volumes(ctx context.Context) ([]VolumeInfo, error) {
+	mask, err := windows.GetLogicalDrives()
+	if err != nil {
+		return nil, fmt.Errorf("GetLogicalDrives: %w", err)
+	}
+
+	var volumes []VolumeInfo
+	for i := 0; i < 26; i++ {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		drive := string(rune('A'+i)) + ":"
+		root, err := windows.UTF16PtrFromString(drive + `\`)
+		if err != nil {
+			continue
+		}
+		driveType, ok := driveTypes[windows.GetDriveType(root)]
+		if !ok {
+			continue
+		}
+		var available, total, free uint64
+		// Drives without media (empty card readers, optical drives) fail here and are skipped
+		if err := windows.GetDiskFreeSpaceEx(root, &available, &total, &free); err != nil || total == 0 {
+			continue
+		}
+		var fsName [windows.MAX_PATH + 1]uint16
+		windows.GetVolumeInformation(root, nil, 0, nil, nil, nil, &fsName[0], uint32(len(fsName)))
+		volumes = append(volumes, VolumeInfo{
+			Drive:      drive,
+			Total:      total,
+			Free:       free,
+			FileSystem: windows.UTF16ToString(fsName[:]),
+			Type:       driveType,
+		})
+	}
+	return volumes, nil
+}
+
+// OSVersion implements SystemInfoProvider. The product name comes from the registry and
+// the build number from RtlGetVersion, which unlike GetVersionEx is not subject to
+// compatibility shims.
+func (NativeSystemInfo) OSVersion(ctx context.Context) (string, error) {
+	info := windows.RtlGetVersion()
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return fmt.Sprintf("Microsoft Windows %d.%d (build %d)", info.MajorVersion, info.MinorVersion, info.BuildNumber), nil
+	}
+	defer k.Close()
+
+	product, _, _ := k.GetStringValue("ProductName")
+	// Windows 11 still reports "Windows 10" as its product name
+	if info.BuildNumber >= 22000 {
+		product = strings.Replace(product, "Windows 10", "Windows 11", 1)
+	}
+	release, _, err := k.GetStringValue("DisplayVersion")
+	if err != nil {
+		release, _, _ = k.GetStringValue("ReleaseId")
+	}
+
+	version := "Microsoft " + product
+	if release != "" {
+		version += " " + release
+	}
+	return version + " (build " + strconv.FormatUint(uint64(info.BuildNumber), 10) + ")", nil
+}
+
+// BootTime implements SystemInfoProvider
+func (NativeSystemInfo) BootTime(ctx context.Context) (time.Time, error) {
+	if err := procGetTickCount64.Find(); err != nil {
+		return time.Time{}, err
+	}
+	lo, hi, _ := procGetTickCount64.Call()
+	ms := uint64(lo)
+	// On 32-bit Windows the upper half of the 64-bit result is returned in EDX
+	if strconv.IntSize == 32 {
+		ms |= uint64(hi) << 32
+	}
+	return time.Now().Add(-time.Duration(ms) * time.Millisecond).Truncate(time.Second), nil
+}
+
+// memoryStatusEx mirrors MEMORYSTATUSEX
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// Memory implements SystemInfoProvider. GlobalMemoryStatusEx reports the commit limit
+// as TotalPageFile.
+func (NativeSystemInfo) Memory(ctx context.Context) (MemoryInfo, error) {
+	status := memoryStatusEx{Length: uint32(unsafe.Sizeof(memoryStatusEx{}))}
+	if r, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))); r == 0 {
+		return MemoryInfo{}, fmt.Errorf("GlobalMemoryStatusEx: %w", err)
+	}
+	commitUsed := status.TotalPageFile - status.AvailPageFile
+	return newMemoryInfo(status.TotalPhys, status.AvailPhys, status.TotalPageFile, commitUsed), nil
+}
+
+// systemTimes returns the busy and total processor time across all processors
+func systemTimes() (busy, total uint64, err error) {
+	var idle, kernel, user windows.Filetime
+	if r, _, err := procGetSystemTimes.Call(uintptr(unsafe.Pointer(&idle)), uintptr(unsafe.Pointer(&kernel)), uintptr(unsafe.Pointer(&user))); r == 0 {
+		return 0, 0, fmt.Errorf("GetSystemTimes: %w", err)
+	}
+	ticks := func(ft windows.Filetime) uint64 { return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime) }
+	// Kernel time includes idle time
+	total = ticks(kernel) + ticks(user)
+	return total - ticks(idle), total, nil
+}
+
+// CPULoad implements SystemInfoProvider
+func (NativeSystemInfo) CPULoad(ctx context.Context) (float64, error) {
+	busy1, total1, err := systemTimes()
+	if err != nil {
+		return 0, err
+	}
+	if err := SleepContext(ctx, cpuSampleInterval); err != nil {
+		return 0, err
+	}
+	busy2, total2, err := systemTimes()
+	if err != nil {
+		return 0, err
+	}
+	if total2 <= total1 || busy2 < busy1 {
+		return 0, nil
+	}
+	return float64(busy2-busy1) / float64(total2-total1) * 100, nil
+}
+
+// systemPageFileInformation mirrors SYSTEM_PAGEFILE_INFORMATION; sizes are in pages
+type systemPageFileInformation struct {
+	NextEntryOffset uint32
+	TotalSize       uint32
+	TotalInUse      uint32
+	PeakUsage       uint32
+	PageFileName    windows.NTUnicodeString
+}
+
+// PageFiles implements SystemInfoProvider
+func (NativeSystemInfo) PageFiles(ctx context.Context) ([]PageFileInfo, error) {
+	buf := make([]byte, 4096)
+	var needed uint32
+	for {
+		err := windows.NtQuerySystemInformation(windows.SystemPageFileInformation, unsafe.Pointer(&buf[0]), uint32(len(buf)), &needed)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, windows.STATUS_INFO_LENGTH_MISMATCH) || len(buf) >= 1<<20 {
+			return nil, fmt.Errorf("NtQuerySystemInformation: %w", err)
+		}
+		buf = make([]byte, max(int(needed), 2*len(buf)))
+	}
+	if needed == 0 {
+		// No page file is configured
+		return nil, nil
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	var files []PageFileInfo
+	for offset := uint32(0); ; {
+		info := (*systemPageFileInformation)(unsafe.Pointer(&buf[offset]))
+		files = append(files, PageFileInfo{
+			Path:       strings.TrimPrefix(info.PageFileName.String(), `\??\`),
+			TotalBytes: uint64(info.TotalSize) * pageSize,
+			UsedBytes:  uint64(info.TotalInUse) * pageSize,
+			PeakBytes:  uint64(info.PeakUsage) * pageSize,
+		})
+		if info.NextEntryOffset == 0 {
+			break
+		}
+		offset += info.NextEntryOffset
+	}
+	return files, nil
+}
+
+// PendingReboot implements SystemInfoProvider
+func (NativeSystemInfo) PendingReboot(ctx context.Context) (PendingReboot, error) {
+	keyExists := func(path string) (bool, error) {
+		k, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE|registry.WOW64_64KEY)
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		k.Close()
+		return true, nil
+	}
+
+	var flags PendingReboot
+	var err error
+	if flags.ComponentServicing, err = keyExists(`SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`); err != nil {
+		return flags, err
+	}
+	if flags.WindowsUpdate, err = keyExists(`SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`); err != nil {
+		return flags, err
+	}
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return flags, err
+	}
+	defer k.Close()
+	renames, _, err := k.GetStringsValue("PendingFileRenameOperations")
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return flags, err
+	}
+	flags.FileRenames = len(renames) > 0
+	return flags, nil
+}
+
+// StoppedAutoStartServices implements SystemInfoProvider. The service manager is opened
+// with enumerate rights only, so this works without administrator privileges.
+func (NativeSystemInfo) StoppedAutoStartServices(ctx context.Context) ([]ServiceInfo, error) {
+	h, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_ENUMERATE_SERVICE)
+	if err != nil {
+		return nil, fmt.Errorf("OpenSCManager: %w", err)
+	}
+	m := &mgr.Mgr{Handle: h}
+	defer m.Disconnect()
+
+	names, err := m.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	var stopped []ServiceInfo
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return stopped, err
+		}
+		namePtr, err := windows.UTF16PtrFromString(name)
+		if err != nil {
+			continue
+		}
+		sh, err := windows.OpenService(h, namePtr, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_QUERY_STATUS)
+		if err != nil {
+			continue
+		}
+		s := &mgr.Service{Name: name, Handle: sh}
+		config, err := s.Config()
+		if err != nil || config.StartType != mgr.StartAutomatic || hasStartTriggers(sh) {
+			s.Close()
+			continue
+		}
+		status, err := s.Query()
+		s.Close()
+		if err != nil || status.State == windows.SERVICE_RUNNING {
+			continue
+		}
+		// Delayed-start services commonly do their work and exit
+		if config.DelayedAutoStart && status.State == windows.SERVICE_STOPPED && status.Win32ExitCode == 0 && status.ServiceSpecificExitCode == 0 {
+			continue
+		}
+		stopped = append(stopped, ServiceInfo{Name: name, DisplayName: config.DisplayName, DelayedStart: config.DelayedAutoStart})
+	}
+	return stopped, nil
+}
+
+// hasStartTriggers reports whether a service is trigger-started, i.e. started and
+// stopped by the system on events such as a device arrival. Such services are
+// normally stopped.
+func hasStartTriggers(h windows.Handle) bool {
+	var needed uint32
+	err := windows.QueryServiceConfig2(h, windows.SERVICE_CONFIG_TRIGGER_INFO, nil, 0, &needed)
+	if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) || needed < 4 {
+		return false
+	}
+	buf := make([]byte, needed)
+	if err := windows.QueryServiceConfig2(h, windows.SERVICE_CONFIG_TRIGGER_INFO, &buf[0], needed, &needed); err != nil {
+		return false
+	}
+	// SERVICE_TRIGGER_INFO begins with the trigger count
+	return *(*uint32)(unsafe.Pointer(&buf[0])) > 0
+}
+
+// lastInputInfo mirrors LASTINPUTINFO
+type lastInputInfo struct {
+	Size uint32
+	Time uint32
+}
+
+// IdleTime implements SystemInfoProvider. GetLastInputInfo only sees input in the
+// caller's session, so a service in session 0 always appears idle.
+func (NativeSystemInfo) IdleTime(ctx context.Context) (time.Duration, error) {
+	info := lastInputInfo{Size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if r, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0, fmt.Errorf("GetLastInputInfo: %w", err)
+	}
+	now, _, _ := procGetTickCount.Call()
+	// Both are 32-bit tick counts, so the subtraction survives the 49-day wraparound
+	return time.Duration(uint32(now)-info.Time) * time.Millisecond, nil
+}
+
+// systemPowerStatus mirrors SYSTEM_POWER_STATUS
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// OnACPower implements SystemInfoProvider; an unknown line status counts as AC power
+func (NativeSystemInfo) OnACPower(ctx context.Context) (bool, error) {
+	var status systemPowerStatus
+	if r, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status))); r == 0 {
+		return false, fmt.Errorf("GetSystemPowerStatus: %w", err)
+	}
+	return status.ACLineStatus != 0, nil
+}
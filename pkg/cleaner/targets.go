@@ -0,0 +1,127 @@
+package cleaner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Target is a user-declared cleanup location from the `targets:` config section.
+// Each target is registered as an operation alongside the built-in ones.
+type Target struct {
+	// ID is the command name, e.g. "gradle-cache"
+	ID string `yaml:"id"`
+	// Name is the display name; defaults to "Clean <id>"
+	Name string `yaml:"name"`
+	// Description is shown in help and menus; defaults to the path
+	Description string `yaml:"description"`
+	// Path is the directory to clean; %VAR% and $VAR environment references are expanded
+	Path string `yaml:"path"`
+	// Patterns, when non-empty, only removes files matching one of these globs
+	Patterns []string `yaml:"patterns"`
+	// Exclude never removes matching files or descends into matching directories
+	Exclude []string `yaml:"exclude"`
+	// MaxAge only removes files not modified for at least this long
+	MaxAge time.Duration `yaml:"max_age"`
+	// Recursive descends into subdirectories
+	Recursive bool `yaml:"recursive"`
+	// DeleteDirectories also removes subdirectories once they are emptied (implies Recursive)
+	DeleteDirectories bool `yaml:"delete_directories"`
+	// RequiresAdmin marks targets under protected locations
+	RequiresAdmin bool `yaml:"requires_admin"`
+	// Timeout overrides the global timeout for this target
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Policy converts the target's rules into a CleanPolicy
+func (t Target) Policy() CleanPolicy {
+	return CleanPolicy{
+		Recursive:      t.Recursive || t.DeleteDirectories,
+		MinAge:         t.MaxAge,
+		Include:        t.Patterns,
+		Exclude:        t.Exclude,
+		PruneEmptyDirs: t.DeleteDirectories,
+	}
+}
+
+// Validate checks that the target is complete and its patterns are well formed
+func (t Target) Validate() error {
+	if t.ID == "" {
+		return errors.New("target is missing an id")
+	}
+	if err := ValidateID(t.ID); err != nil {
+		return fmt.Errorf("target %w", err)
+	}
+	if t.Path == "" {
+		return fmt.Errorf("target %q is missing a path", t.ID)
+	}
+	if err := t.Policy().Validate(); err != nil {
+		return fmt.Errorf("target %q: %w", t.ID, err)
+	}
+	return nil
+}
+
+// Operation returns the registry entry that cleans this target
+func (t Target) Operation() Operation {
+	name := t.Name
+	if name == "" {
+		name = "Clean " + t.ID
+	}
+	description := t.Description
+	if description == "" {
+		description = "Clean " + t.Path
+	}
+	return Operation{
+		ID:            t.ID,
+		Name:          name,
+		Description:   description,
+		Timeout:       t.Timeout,
+		RequiresAdmin: t.RequiresAdmin,
+		Destructive:   true,
+		Run: func(ctx context.Context, opts Options) (*OperationResult, error) {
+			return CleanTarget(ctx, opts, t)
+		},
+	}
+}
+
+// CleanTarget removes the files in a custom target selected by its rules.
+// A target whose directory does not exist is reported as skipped, not as a failure.
+func CleanTarget(ctx context.Context, opts Options, t Target) (*OperationResult, error) {
+	res := newResult(opts)
+	defer res.finish()
+
+	dir := ExpandPath(t.Path)
+	if opts.Verbose {
+		fmt.Fprintf(opts.Writer(), "[VERBOSE] Cleaning target %s: %s\n", t.ID, dir)
+	}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		res.skip(dir, "directory not found")
+		return res, nil
+	}
+	if err := cleanDirectory(ctx, dir, t.Policy(), opts, res); err != nil {
+		return res, err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintf(opts.Writer(), "[DRY-RUN] Would delete %d items totalling %s\n", res.ItemsRemoved, FormatBytes(float64(res.BytesFreed)))
+	}
+	return res, nil
+}
+
+var windowsEnvRef = regexp.MustCompile(`%([A-Za-z0-9_()]+)%`)
+
+// ExpandPath expands Windows-style %VAR% and Unix-style $VAR environment references.
+// Unknown %VAR% references are left untouched.
+func ExpandPath(path string) string {
+	path = windowsEnvRef.ReplaceAllStringFunc(path, func(ref string) string {
+		if value, ok := os.LookupEnv(ref[1 : len(ref)-1]); ok {
+			return value
+		}
+		return ref
+	})
+	return os.ExpandEnv(path)
+}
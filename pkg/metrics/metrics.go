@@ -0,0 +1,262 @@
+// Package metrics publishes wincleaner's system status, health and operation history
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/windows_health/pkg/cleaner"
+)
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Snapshot is everything published in one scrape
+type Snapshot struct {
+	Time    time.Time
+	Version string
+	Status  *cleaner.SystemStatus
+	Health  *cleaner.HealthReport
+	// History supplies the per-operation counters
+	History *cleaner.HistoryReport
+	// Duration is how long collecting the snapshot took
+	Duration time.Duration
+}
+
+// writer emits metric families, writing HELP and TYPE once per family
+type writer struct {
+	w      *bufio.Writer
+	family string
+}
+
+// sample writes one sample, preceded by the family header when the family changes.
+// labels alternates names and values.
+func (m *writer) sample(name, kind, help string, value float64, labels ...string) {
+	if name != m.family {
+		fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		m.family = name
+	}
+	m.w.WriteString(name)
+	if len(labels) > 0 {
+		m.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				m.w.WriteByte(',')
+			}
+			fmt.Fprintf(m.w, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		m.w.WriteByte('}')
+	}
+	m.w.WriteByte(' ')
+	m.w.WriteString(formatValue(value))
+	m.w.WriteByte('\n')
+}
+
+// escapeLabel escapes a label value as the exposition format requires
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatValue formats a sample value, spelling out the special floats
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// boolValue converts a flag to 0 or 1
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// unixSeconds converts a time to fractional seconds since the epoch
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// Write renders a snapshot. Sections missing from the snapshot are left out.
+func Write(w io.Writer, s Snapshot) error {
+	m := &writer{w: bufio.NewWriter(w)}
+
+	m.sample("wincleaner_build_info", "gauge", "Version of wincleaner serving these metrics.", 1, "version", s.Version)
+	m.sample("wincleaner_scrape_timestamp_seconds", "gauge", "When the system status was last collected.", unixSeconds(s.Time))
+	m.sample("wincleaner_scrape_duration_seconds", "gauge", "How long collecting the system status took.", s.Duration.Seconds())
+
+	if st := s.Status; st != nil {
+		writeStatus(m, st)
+	}
+
+	if h := s.Health; h != nil {
+		m.sample("wincleaner_health_score", "gauge", "Health score from 100 (healthy) down to 0.", float64(h.Score))
+		counts := map[string]int{cleaner.SeverityOK: 0, cleaner.SeverityWarn: 0, cleaner.SeverityCrit: 0}
+		for _, f := range h.Findings {
+			counts[f.Severity]++
+		}
+		for _, severity := range []string{cleaner.SeverityOK, cleaner.SeverityWarn, cleaner.SeverityCrit} {
+			m.sample("wincleaner_health_findings", "gauge", "Number of health findings by severity.", float64(counts[severity]), "severity", severity)
+		}
+		for _, f := range h.Problems() {
+			m.sample("wincleaner_health_problem", "gauge", "Health findings that are not OK (1 = warn, 2 = crit).", severityValue(f.Severity), "check", f.Check, "subject", f.Subject, "operation", f.Operation)
+		}
+	}
+
+	if hist := s.History; hist != nil {
+		ops := hist.Operations
+		for _, op := range ops {
+			m.sample("wincleaner_operation_runs_total", "counter", "Recorded runs of each operation.", float64(op.Runs), "operation", op.Operation)
+		}
+		for _, op := range ops {
+			m.sample("wincleaner_operation_failures_total", "counter", "Recorded runs of each operation that did not succeed.", float64(op.Failures), "operation", op.Operation)
+		}
+		for _, op := range ops {
+			m.sample("wincleaner_operation_freed_bytes_total", "counter", "Bytes freed by each operation.", float64(op.BytesFreed), "operation", op.Operation)
+		}
+		for _, op := range ops {
+			m.sample("wincleaner_operation_last_run_timestamp_seconds", "gauge", "When each operation last ran.", unixSeconds(op.LastRun), "operation", op.Operation)
+		}
+		for _, op := range ops {
+			m.sample("wincleaner_operation_last_success", "gauge", "Whether the last run of each operation succeeded.", boolValue(op.LastStatus == cleaner.StatusSuccess), "operation", op.Operation)
+		}
+	}
+
+	return m.w.Flush()
+}
+
+// writeStatus renders the system status gauges
+func writeStatus(m *writer, st *cleaner.SystemStatus) {
+	drives := st.Drives()
+	for _, drive := range drives {
+		info := st.DiskSpace[drive]
+		m.sample("wincleaner_disk_size_bytes", "gauge", "Total size of each volume.", float64(info.TotalBytes), "drive", drive, "type", info.DriveType, "filesystem", info.FileSystem)
+	}
+	for _, drive := range drives {
+		m.sample("wincleaner_disk_free_bytes", "gauge", "Free space on each volume.", float64(st.DiskSpace[drive].FreeBytes), "drive", drive)
+	}
+
+	if !st.LastBootTime.IsZero() {
+		m.sample("wincleaner_boot_time_seconds", "gauge", "When the system last started, in seconds since the epoch.", unixSeconds(st.LastBootTime))
+		m.sample("wincleaner_uptime_seconds", "gauge", "Time since the system last started.", st.Uptime.Seconds())
+	}
+	if r := st.PendingReboot; r != nil {
+		const help = "Whether a restart is pending, by the flag that requires it."
+		m.sample("wincleaner_pending_reboot", "gauge", help, boolValue(r.ComponentServicing), "reason", "component_servicing")
+		m.sample("wincleaner_pending_reboot", "gauge", help, boolValue(r.WindowsUpdate), "reason", "windows_update")
+		m.sample("wincleaner_pending_reboot", "gauge", help, boolValue(r.FileRenames), "reason", "file_renames")
+	}
+	if mem := st.Memory; mem != nil {
+		m.sample("wincleaner_memory_total_bytes", "gauge", "Installed physical memory.", float64(mem.TotalBytes))
+		m.sample("wincleaner_memory_available_bytes", "gauge", "Available physical memory.", float64(mem.AvailableBytes))
+		m.sample("wincleaner_commit_limit_bytes", "gauge", "Commit limit (physical memory plus page files).", float64(mem.CommitLimitBytes))
+		m.sample("wincleaner_commit_used_bytes", "gauge", "Committed memory.", float64(mem.CommitUsedBytes))
+	}
+	if st.CPULoadPercent != nil {
+		m.sample("wincleaner_cpu_load_ratio", "gauge", "Processor utilization sampled at collection time, from 0 to 1.", *st.CPULoadPercent/100)
+	}
+	for _, pf := range st.PageFiles {
+		m.sample("wincleaner_page_file_size_bytes", "gauge", "Size of each page file.", float64(pf.TotalBytes), "path", pf.Path)
+	}
+	for _, pf := range st.PageFiles {
+		m.sample("wincleaner_page_file_used_bytes", "gauge", "Used space in each page file.", float64(pf.UsedBytes), "path", pf.Path)
+	}
+	if st.TempFolderBytes != nil {
+		m.sample("wincleaner_temp_folder_bytes", "gauge", "Combined size of the temporary directories.", float64(*st.TempFolderBytes))
+	}
+	for _, svc := range st.StoppedServices {
+		m.sample("wincleaner_service_stopped", "gauge", "Automatic-start services that are not running.", 1, "name", svc.Name, "delayed_start", strconv.FormatBool(svc.DelayedStart))
+	}
+	if d := st.DNS; d != nil {
+		m.sample("wincleaner_dns_resolves", "gauge", "Whether the DNS probe host could be resolved.", boolValue(d.Resolved), "host", d.Host)
+	}
+
+	collectors := make([]string, 0, len(st.Errors))
+	for name := range st.Errors {
+		collectors = append(collectors, name)
+	}
+	sort.Strings(collectors)
+	for _, name := range collectors {
+		m.sample("wincleaner_collector_error", "gauge", "Status collectors that failed in the last collection.", 1, "collector", name)
+	}
+}
+
+// severityValue maps a severity to the value of wincleaner_health_problem
+func severityValue(severity string) float64 {
+	if severity == cleaner.SeverityCrit {
+		return 2
+	}
+	return 1
+}
+
+// Exporter refreshes a Snapshot on an interval and serves the latest one over HTTP
+type Exporter struct {
+	// Collect produces a new snapshot
+	Collect func(ctx context.Context) (Snapshot, error)
+	// Interval is the time between refreshes
+	Interval time.Duration
+	// OnError is called when a refresh fails; the previous snapshot keeps being served
+	OnError func(err error)
+
+	mu       sync.RWMutex
+	snapshot *Snapshot
+}
+
+// Run refreshes the snapshot immediately and then every Interval until ctx is done
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+	for {
+		e.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh collects and stores one snapshot
+func (e *Exporter) refresh(ctx context.Context) {
+	start := time.Now()
+	snap, err := e.Collect(ctx)
+	if err != nil {
+		if e.OnError != nil && ctx.Err() == nil {
+			e.OnError(err)
+		}
+		return
+	}
+	snap.Duration = time.Since(start)
+	e.mu.Lock()
+	e.snapshot = &snap
+	e.mu.Unlock()
+}
+
+// ServeHTTP implements http.Handler, writing the latest snapshot
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	snap := e.snapshot
+	e.mu.RUnlock()
+	if snap == nil {
+		http.Error(w, "metrics are not collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	Write(w, *snap)
+}
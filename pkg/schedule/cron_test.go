@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// at returns a UTC time in October 2026; the 17th is a Saturday
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "* * * * *"},
+		{expr: "0 3 * * 0"},
+		{expr: "*/15 9-17 * * mon-fri"},
+		{expr: "0,30 1-23/2 1,15 jan-jun,DEC SUN"},
+		{expr: "5/10 * * * 7"},
+		{expr: "@daily"},
+		{expr: "  @Weekly "},
+		{expr: "", wantErr: "must have 5 fields"},
+		{expr: "* * * *", wantErr: "must have 5 fields"},
+		{expr: "* * * * * *", wantErr: "must have 5 fields"},
+		{expr: "@fortnightly", wantErr: "must have 5 fields"},
+		{expr: "60 * * * *", wantErr: "minute"},
+		{expr: "* 24 * * *", wantErr: "hour"},
+		{expr: "* * 0 * *", wantErr: "day of month"},
+		{expr: "* * 32 * *", wantErr: "day of month"},
+		{expr: "* * * 13 *", wantErr: "month"},
+		{expr: "* * * foo *", wantErr: "month"},
+		{expr: "* * * * 8", wantErr: "day of week"},
+		{expr: "*/0 * * * *", wantErr: "invalid step"},
+		{expr: "*/x * * * *", wantErr: "invalid step"},
+		{expr: "30-10 * * * *", wantErr: "invalid range"},
+		{expr: "1,,2 * * * *", wantErr: "minute"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			} else if c.String() != tt.expr {
+				t.Errorf("ParseCron(%q).String() = %q", tt.expr, c.String())
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseCron(%q) error %v, want one containing %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "every minute", expr: "* * * * *", from: at(17, 10, 5), want: at(17, 10, 6)},
+		{name: "strictly after", expr: "30 10 * * *", from: at(17, 10, 30), want: at(18, 10, 30)},
+		{name: "seconds are dropped", expr: "* * * * *", from: at(17, 10, 5).Add(59 * time.Second), want: at(17, 10, 6)},
+		{name: "later today", expr: "0 3,15 * * *", from: at(17, 10, 0), want: at(17, 15, 0)},
+		{name: "tomorrow", expr: "0 3 * * *", from: at(17, 10, 0), want: at(18, 3, 0)},
+		{name: "minute step", expr: "*/15 * * * *", from: at(17, 10, 16), want: at(17, 10, 30)},
+		{name: "step from a start", expr: "5/20 * * * *", from: at(17, 10, 46), want: at(17, 11, 5)},
+		{name: "hour range rolls to the next day", expr: "0 9-17 * * *", from: at(17, 17, 1), want: at(18, 9, 0)},
+		{name: "weekday names", expr: "0 9 * * mon-fri", from: at(17, 8, 0), want: at(19, 9, 0)},
+		{name: "sunday as 7", expr: "0 3 * * 7", from: at(17, 12, 0), want: at(18, 3, 0)},
+		{name: "weekly macro", expr: "@weekly", from: at(17, 12, 0), want: at(18, 0, 0)},
+		{name: "monthly macro", expr: "@monthly", from: at(17, 12, 0), want: time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "yearly macro", expr: "@yearly", from: at(17, 12, 0), want: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "month names", expr: "0 0 1 feb *", from: at(17, 12, 0), want: time.Date(2027, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "31st skips short months", expr: "0 0 31 * *", from: at(31, 12, 0), want: time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 0 29 2 *", from: at(17, 12, 0), want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// With both day fields restricted, either one matching is enough: the 20th is a Tuesday
+		{name: "day of month or weekday", expr: "0 0 20 * sat", from: at(17, 12, 0), want: at(20, 0, 0)},
+		{name: "weekday or day of month", expr: "0 0 28 * sun", from: at(17, 12, 0), want: at(18, 0, 0)},
+		{name: "starred day of month defers to weekday", expr: "0 0 * * tue", from: at(17, 12, 0), want: at(20, 0, 0)},
+		{name: "never", expr: "0 0 30 2 *", from: at(17, 12, 0), want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+// Next keeps the location of the time it is given
+func TestCronNextLocation(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	c, err := ParseCron("0 3 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Next(time.Date(2026, time.October, 17, 2, 0, 0, 0, zone))
+	if want := time.Date(2026, time.October, 17, 3, 0, 0, 0, zone); !got.Equal(want) || got.Location() != zone {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    *QuietHours
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "  ", want: nil},
+		{in: "08:00-18:00", want: &QuietHours{Start: 8 * time.Hour, End: 18 * time.Hour}},
+		{in: "22:30 - 06:15", want: &QuietHours{Start: 22*time.Hour + 30*time.Minute, End: 6*time.Hour + 15*time.Minute}},
+		{in: "08:00", wantErr: true},
+		{in: "8am-6pm", wantErr: true},
+		{in: "08:00-25:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuietHours(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseQuietHours(%q) error %v, want error: %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("ParseQuietHours(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	day := &QuietHours{Start: 8 * time.Hour, End: 18 * time.Hour}
+	night := &QuietHours{Start: 22 * time.Hour, End: 6 * time.Hour}
+	tests := []struct {
+		name         string
+		quiet        *QuietHours
+		t            time.Time
+		wantContains bool
+		wantEnd      time.Time
+	}{
+		{name: "before a daytime window", quiet: day, t: at(17, 7, 59)},
+		{name: "start of a daytime window", quiet: day, t: at(17, 8, 0), wantContains: true, wantEnd: at(17, 18, 0)},
+		{name: "inside a daytime window", quiet: day, t: at(17, 17, 59), wantContains: true, wantEnd: at(17, 18, 0)},
+		{name: "end of a daytime window", quiet: day, t: at(17, 18, 0)},
+		{name: "evening in an overnight window", quiet: night, t: at(17, 23, 0), wantContains: true, wantEnd: at(18, 6, 0)},
+		{name: "morning in an overnight window", quiet: night, t: at(17, 5, 30), wantContains: true, wantEnd: at(17, 6, 0)},
+		{name: "outside an overnight window", quiet: night, t: at(17, 12, 0)},
+		{name: "no quiet hours", quiet: nil, t: at(17, 12, 0)},
+		{name: "empty window", quiet: &QuietHours{Start: 8 * time.Hour, End: 8 * time.Hour}, t: at(17, 8, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiet.Contains(tt.t); got != tt.wantContains {
+				t.Fatalf("Contains(%s) = %v, want %v", tt.t, got, tt.wantContains)
+			}
+			if !tt.wantContains {
+				return
+			}
+			if got := tt.quiet.EndAfter(tt.t); !got.Equal(tt.wantEnd) {
+				t.Errorf("EndAfter(%s) = %s, want %s", tt.t, got, tt.wantEnd)
+			}
+		})
+	}
+}